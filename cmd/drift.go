@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ichaly/ideabase/gql"
+	"github.com/ichaly/ideabase/ioc"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+const (
+	fromFlag = "from"
+	toFlag   = "to"
+	jsonFlag = "json"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Compare metadata snapshots and exit non-zero on breaking GraphQL changes.",
+	Long: "Compare two metadata snapshots built from the metadata file (file), the live database (db) " +
+		"or the registered GORM entities (entity), and report added or removed classes and fields, " +
+		"type, nullability and relation changes.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString(fromFlag)
+		to, _ := cmd.Flags().GetString(toFlag)
+		k, c, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		var db *gorm.DB
+		if from == gql.SnapshotDatabase || to == gql.SnapshotDatabase {
+			if db, err = openDatabase(c); err != nil {
+				return err
+			}
+		}
+		before, err := gql.NewSnapshot(k, db, from, ioc.Entities()...)
+		if err != nil {
+			return fmt.Errorf("load %s snapshot: %w", from, err)
+		}
+		after, err := gql.NewSnapshot(k, db, to, ioc.Entities()...)
+		if err != nil {
+			return fmt.Errorf("load %s snapshot: %w", to, err)
+		}
+
+		changes := gql.Diff(before, after)
+		if asJSON, _ := cmd.Flags().GetBool(jsonFlag); asJSON {
+			if changes == nil {
+				changes = []gql.Change{}
+			}
+			data, err := json.MarshalIndent(changes, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		} else {
+			for _, v := range changes {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), v)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d changes from %s to %s\n", len(changes), from, to)
+		}
+		if gql.HasBreaking(changes) {
+			return errors.New("breaking changes detected")
+		}
+		return nil
+	},
+}
+
+func init() {
+	driftCmd.Flags().String(fromFlag, gql.SnapshotFile, "baseline snapshot: file, db or entity")
+	driftCmd.Flags().String(toFlag, gql.SnapshotDatabase, "current snapshot: file, db or entity")
+	driftCmd.Flags().Bool(jsonFlag, false, "print changes as JSON")
+	runCmd.AddCommand(driftCmd)
+}
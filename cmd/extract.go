@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ichaly/ideabase/gql"
+	"github.com/spf13/cobra"
+)
+
+const outputFlag = "output"
+
+var extractCmd = &cobra.Command{
+	Use:   "extract <dir>",
+	Short: "Extract trusted documents manifest from .graphql files.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, err := gql.ExtractManifest(args[0])
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString(outputFlag)
+		if output == "" {
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return err
+		}
+		if err = os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "extracted %d documents to %s\n", len(manifest), output)
+		return err
+	},
+}
+
+func init() {
+	extractCmd.Flags().StringP(outputFlag, "o", "", "write manifest to file instead of stdout")
+	runCmd.AddCommand(extractCmd)
+}
@@ -3,7 +3,9 @@ module github.com/ichaly/ideabase/cmd
 go 1.25.0
 
 require (
+	github.com/ichaly/ideabase/gql v0.0.0-20260110145933-e564f1aca14f
 	github.com/ichaly/ideabase/ioc v0.0.0-20260110145933-e564f1aca14f
+	github.com/ichaly/ideabase/std v0.0.0-20260110145933-e564f1aca14f
 	github.com/ichaly/ideabase/utl v0.0.0-20260110145933-e564f1aca14f
 	github.com/samber/lo v1.52.0
 	github.com/spf13/cobra v1.10.2
@@ -34,9 +36,7 @@ require (
 	github.com/huandu/go-clone v1.7.3 // indirect
 	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/ichaly/ideabase/bus v0.0.0-20260110145933-e564f1aca14f // indirect
-	github.com/ichaly/ideabase/gql v0.0.0-20260110145933-e564f1aca14f // indirect
 	github.com/ichaly/ideabase/log v0.0.0-20260110145933-e564f1aca14f // indirect
-	github.com/ichaly/ideabase/std v0.0.0-20260110145933-e564f1aca14f // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/invzhi/next v1.1.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ichaly/ideabase/std"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+const dirFlag = "dir"
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage versioned database migrations.",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := openMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		done, err := m.Up(cmd.Context())
+		for _, v := range done {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "applied %s\n", v)
+		}
+		if err == nil && len(done) == 0 {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "no pending migrations")
+		}
+		return err
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [n]",
+	Short: "Roll back the last n applied migrations, 1 by default.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) > 0 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 1 {
+				return fmt.Errorf("invalid count %q", args[0])
+			}
+			n = v
+		}
+		m, err := openMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		done, err := m.Down(cmd.Context(), n)
+		for _, v := range done {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "rolled back %s\n", v)
+		}
+		return err
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := openMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		list, err := m.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, v := range list {
+			status := "pending"
+			if v.AppliedAt != nil {
+				status = "applied at " + v.AppliedAt.Format(time.DateTime)
+			}
+			if v.Modified {
+				status += " (modified)"
+			}
+			if v.Missing {
+				status += " (missing)"
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", v.Version, v.Name, status)
+		}
+		return w.Flush()
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create up and down SQL files for a new migration.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString(dirFlag)
+		if dir == "" {
+			_, c, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			dir = c.Migrate.Dir
+		}
+		files, err := std.CreateMigration(dir, args[0])
+		for _, v := range files {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "created %s\n", v)
+		}
+		return err
+	},
+}
+
+func init() {
+	migrateCreateCmd.Flags().StringP(dirFlag, "d", "", "directory for migration files, defaults to app.migrate.dir")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateCreateCmd)
+	runCmd.AddCommand(migrateCmd)
+}
+
+// loadConfig 读取配置文件
+func loadConfig(cmd *cobra.Command) (*std.Konfig, *std.Config, error) {
+	k, err := std.NewKonfig(std.WithFilePath(configPath(cmd)))
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := std.NewConfig(k)
+	return k, c, err
+}
+
+// openDatabase 按配置连接主库，只建立连接，不启动应用，也不自动执行迁移
+func openDatabase(c *std.Config) (*gorm.DB, error) {
+	return std.NewMigrationDatabase(c)
+}
+
+// openMigrator 按配置连接数据库创建迁移执行器
+func openMigrator(cmd *cobra.Command) (*std.Migrator, error) {
+	_, c, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(c)
+	if err != nil {
+		return nil, err
+	}
+	return std.NewMigrator(db, c), nil
+}
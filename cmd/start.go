@@ -17,16 +17,21 @@ var runCmd = &cobra.Command{
 	Aliases: []string{"run", "s", "r"},
 	Short:   "Start Service.",
 	Run: func(cmd *cobra.Command, args []string) {
-		configFile, _ := cmd.Flags().GetString(configFlag)
-		if configFile == "" {
-			configFile = filepath.Join(utl.Root(), "cfg", "config.yml")
-		}
-		ioc.Run(ioc.Supply(configFile))
+		ioc.Run(ioc.Supply(configPath(cmd)))
 	},
 }
 
 func init() {
-	runCmd.Flags().StringP(configFlag, "c", "", "start app with config file")
+	runCmd.PersistentFlags().StringP(configFlag, "c", "", "config file, defaults to cfg/config.yml")
+}
+
+// configPath 返回命令行指定的配置文件，未指定时使用根目录下的 cfg/config.yml
+func configPath(cmd *cobra.Command) string {
+	configFile, _ := cmd.Flags().GetString(configFlag)
+	if configFile == "" {
+		configFile = filepath.Join(utl.Root(), "cfg", "config.yml")
+	}
+	return configFile
 }
 
 func Execute() {
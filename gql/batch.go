@@ -0,0 +1,78 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// batch 处理JSON数组形式的批量请求，按请求顺序返回结果数组
+// 相邻的查询在并发上限内并发执行，变更作为屏障按顺序逐个执行，保证其前后查询看到一致的数据
+func (my *Executor) batch(c fiber.Ctx, body []byte) error {
+	cfg := my.current.Load().metadata.cfg.Executor.Batch
+	var reqs []gqlQuery
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"errors": []gqlerror.Error{*gqlerror.Wrap(err)},
+		})
+	}
+	if cfg.MaxSize <= 0 || len(reqs) == 0 || len(reqs) > cfg.MaxSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"errors": []gqlerror.Error{*gqlerror.Errorf("批量请求的操作数量必须在1到%d之间", cfg.MaxSize)},
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(my.executeBatch(c.Context(), reqs, cfg.Concurrency))
+}
+
+// executeBatch 执行批量请求，concurrency不大于0时查询也逐个执行
+func (my *Executor) executeBatch(ctx context.Context, reqs []gqlQuery, concurrency int) []gqlReply {
+	replies := make([]gqlReply, len(reqs))
+	queries := make([]string, len(reqs))
+	for i, req := range reqs {
+		query, err := my.resolveQuery(ctx, req)
+		if err != nil {
+			replies[i].Errors = gqlerror.List{err}
+			continue
+		}
+		queries[i] = query
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// 并发执行的panic不会被Fiber的恢复中间件捕获，需要转为错误结果
+	run := func(i int) {
+		defer func() {
+			if r := recover(); r != nil {
+				replies[i] = gqlReply{Errors: gqlerror.List{gqlerror.Errorf("执行失败: %v", r)}}
+			}
+		}()
+		replies[i] = my.Execute(ctx, queries[i], reqs[i].Variables, reqs[i].OperationName)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		if replies[i].Errors != nil {
+			continue
+		}
+		if isMutation(queries[i], req.OperationName) {
+			// 等待之前的查询完成后再执行变更
+			wg.Wait()
+			run(i)
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+	return replies
+}
@@ -0,0 +1,71 @@
+package gql
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRequest(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Executor.Batch.MaxSize = 5
+	meta.cfg.Executor.Batch.Concurrency = 2
+	executor := newMockExecutor(t, meta)
+
+	app := fiber.New()
+	executor.Bind(app.Group(executor.Path()))
+	post := func(body string) (int, []gqlReply) {
+		req := httptest.NewRequest(http.MethodPost, executor.Path(), strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		var replies []gqlReply
+		_ = json.Unmarshal(data, &replies)
+		return resp.StatusCode, replies
+	}
+
+	t.Run("按顺序返回结果", func(t *testing.T) {
+		status, replies := post(` [
+			{"query":"{ __schema { queryType { name } } }"},
+			{"query":"{ __schema { mutationType { name } } }"},
+			{"documentId":"unknown"},
+			{"query":"{ __schema { subscriptionType { name } } }"}
+		]`)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, replies, 4)
+		assert.Equal(t, "Query", replies[0].Data["__schema"].(map[string]interface{})["queryType"].(map[string]interface{})["name"])
+		assert.Equal(t, "Mutation", replies[1].Data["__schema"].(map[string]interface{})["mutationType"].(map[string]interface{})["name"])
+		require.Len(t, replies[2].Errors, 1)
+		assert.Equal(t, CODE_TRUSTED_DOCUMENT_NOT_FOUND, replies[2].Errors[0].Extensions["code"])
+		assert.Equal(t, "Subscription", replies[3].Data["__schema"].(map[string]interface{})["subscriptionType"].(map[string]interface{})["name"])
+	})
+
+	t.Run("执行失败不影响其他操作", func(t *testing.T) {
+		// 未配置数据库时变更执行失败，错误只出现在对应位置
+		status, replies := post(`[
+			{"query":"mutation { deleteUser(id: 1) }"},
+			{"query":"{ __schema { queryType { name } } }"}
+		]`)
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, replies, 2)
+		assert.NotEmpty(t, replies[0].Errors)
+		assert.Empty(t, replies[1].Errors)
+	})
+
+	t.Run("超过最大数量", func(t *testing.T) {
+		status, _ := post(`[` + strings.TrimSuffix(strings.Repeat(`{"query":"{ __typename }"},`, 6), ",") + `]`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("空数组", func(t *testing.T) {
+		status, _ := post(`[]`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
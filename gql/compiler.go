@@ -23,15 +23,42 @@ func NewCompiler(m *Metadata, dialects []compiler.Dialect) (*Compiler, error) {
 	return my, nil
 }
 
+// fork 基于新的元数据创建编译器，复用已选定的方言
+func (my *Compiler) fork(m *Metadata) *Compiler {
+	if my == nil {
+		return nil
+	}
+	return &Compiler{meta: m, dialect: my.dialect}
+}
+
 func (my *Compiler) Build(operation *ast.OperationDefinition, variables map[string]interface{}) (string, []any, error) {
+	p, err := my.compile(operation, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.sql, p.args, nil
+}
+
+// compile 编译操作并记录变量绑定的参数槽，结构不依赖变量时编译结果可以缓存
+func (my *Compiler) compile(operation *ast.OperationDefinition, variables map[string]interface{}) (*plan, error) {
 	ctx := compiler.NewContext(my.meta, my.dialect.Quotation(), variables)
+	defer ctx.Release()
+	var err error
 	switch operation.Operation {
 	case ast.Query, ast.Subscription:
-		my.dialect.BuildQuery(ctx, operation.SelectionSet)
+		err = my.dialect.BuildQuery(ctx, operation.SelectionSet)
 	case ast.Mutation:
-		my.dialect.BuildMutation(ctx, operation.SelectionSet)
+		err = my.dialect.BuildMutation(ctx, operation.SelectionSet)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return ctx.String(), ctx.Args(), nil
+	return &plan{
+		sql:       ctx.String(),
+		args:      append([]any(nil), ctx.Args()...),
+		slots:     ctx.Slots(),
+		cacheable: ctx.Cacheable(),
+	}, nil
 }
 
 // selectDialect 选择适合当前数据库的SQL方言
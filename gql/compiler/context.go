@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"sync"
 )
@@ -20,6 +21,14 @@ type Context struct {
 	params    []any
 	hoster    protocol.Hoster
 	variables map[string]interface{}
+	slots     []Slot
+	dynamic   bool
+}
+
+// Slot 参数列表中由变量直接绑定的位置，缓存的编译结果按新的变量重新填充
+type Slot struct {
+	Index int        // 参数下标，从0开始
+	Value *ast.Value // 变量引用，按变量定义解析默认值
 }
 
 // contextPool 用于Context对象池管理，减少GC压力
@@ -52,9 +61,15 @@ func (my *Context) Release() {
 	my.hoster = nil
 	my.variables = nil
 	my.params = my.params[:0]
+	my.slots = nil
+	my.dynamic = false
 	contextPool.Put(my)
 }
 
+// NODE_ID Relay的Node接口要求的全局ID字段名
+const NODE_ID = "id"
+
+// FindField 查找字段，当前角色无权访问的字段视为不存在
 func (my *Context) FindField(className, fieldName string) (*protocol.Field, bool) {
 	if my.hoster == nil {
 		return nil, false
@@ -64,7 +79,20 @@ func (my *Context) FindField(className, fieldName string) (*protocol.Field, bool
 		return nil, false
 	}
 	field, ok := class.Fields[fieldName]
-	return field, ok
+	if !ok && fieldName == NODE_ID {
+		// Relay的Node接口要求id字段，类中没有id字段时映射到唯一主键
+		field = class.PrimaryField()
+		ok = field != nil
+	}
+	if !ok {
+		return nil, false
+	}
+	if role := my.Role(); role != "" {
+		if p, ok := class.Permission(role); !ok || !p.Visible(field) {
+			return nil, false
+		}
+	}
+	return field, true
 }
 
 func (my *Context) TableName(className string) (string, bool) {
@@ -89,6 +117,59 @@ func (my *Context) AddParam(value any) int {
 	return len(my.params)
 }
 
+// Value 按当前变量解析值
+// 用于决定SQL结构的值（如分页、排序方向）引用变量时，编译结果随变量变化而不可缓存
+func (my *Context) Value(value *ast.Value) (any, error) {
+	if hasVariable(value) {
+		my.dynamic = true
+	}
+	return value.Value(my.variables)
+}
+
+// Param 解析值并添加为参数，返回参数索引
+// 直接引用变量的参数记录为参数槽，缓存的编译结果可以按新的变量重新绑定
+func (my *Context) Param(value *ast.Value) (int, error) {
+	if value.Kind != ast.Variable {
+		val, err := my.Value(value)
+		if err != nil {
+			return 0, err
+		}
+		return my.AddParam(val), nil
+	}
+	val, err := value.Value(my.variables)
+	if err != nil {
+		return 0, err
+	}
+	my.slots = append(my.slots, Slot{Index: len(my.params), Value: value})
+	return my.AddParam(val), nil
+}
+
+// Slots 返回由变量绑定的参数槽
+func (my *Context) Slots() []Slot {
+	return my.slots
+}
+
+// Cacheable 编译结果是否只通过参数槽依赖变量
+func (my *Context) Cacheable() bool {
+	return !my.dynamic
+}
+
+// hasVariable 判断值中是否引用了变量
+func hasVariable(value *ast.Value) bool {
+	if value == nil {
+		return false
+	}
+	if value.Kind == ast.Variable {
+		return true
+	}
+	for _, child := range value.Children {
+		if hasVariable(child.Value) {
+			return true
+		}
+	}
+	return false
+}
+
 // String 获取当前SQL字符串
 func (my *Context) String() string {
 	return strings.TrimSpace(my.buf.String())
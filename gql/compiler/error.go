@@ -0,0 +1,39 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Error 编译错误，记录出错的字段或参数在GraphQL文档中的位置
+type Error struct {
+	Position *ast.Position
+	Err      error
+}
+
+func (my *Error) Error() string {
+	return my.Err.Error()
+}
+
+func (my *Error) Unwrap() error {
+	return my.Err
+}
+
+// Errorf 创建带位置的编译错误
+func Errorf(pos *ast.Position, format string, args ...any) error {
+	return &Error{Position: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// WrapError 为错误附加位置，已带位置的错误保留最内层的位置
+func WrapError(pos *ast.Position, err error) error {
+	if err == nil || pos == nil {
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Position: pos, Err: err}
+}
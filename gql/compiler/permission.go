@@ -0,0 +1,124 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/utl"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// 权限相关的保留变量，以$开头不会与客户端变量冲突
+// 执行器在编译前写入当前角色和用户，行过滤模板中的$user和$claims.<声明名>按变量绑定
+const (
+	VAR_ROLE   = "$role"
+	VAR_USER   = "$user"
+	VAR_CLAIMS = "$claims."
+)
+
+// ErrForbidden 当前角色无权执行操作
+var ErrForbidden = errors.New("无权访问")
+
+// Role 返回编译时的角色，未启用权限时为空
+func (my *Context) Role() string {
+	role, _ := my.variables[VAR_ROLE].(string)
+	return role
+}
+
+// Permission 返回当前角色在类上的权限规则，类不存在或未启用权限时返回空规则
+func (my *Context) Permission(className string) (*protocol.Permission, error) {
+	role := my.Role()
+	if role == "" || my.hoster == nil {
+		return nil, nil
+	}
+	class, ok := my.hoster.GetNode(className)
+	if !ok {
+		return nil, nil
+	}
+	p, ok := class.Permission(role)
+	if !ok {
+		return nil, fmt.Errorf("%w: 角色%s不能访问%s", ErrForbidden, role, class.Name)
+	}
+	return p, nil
+}
+
+// Allow 校验当前角色能否在类上执行操作
+func (my *Context) Allow(pos *ast.Position, className, op string) error {
+	p, err := my.Permission(className)
+	if err == nil && !p.Allow(op) {
+		err = fmt.Errorf("%w: 角色%s不能执行%s的%s操作", ErrForbidden, my.Role(), className, op)
+	}
+	if err != nil {
+		return &Error{Position: pos, Err: err}
+	}
+	return nil
+}
+
+// Filter 返回当前角色在类上的行过滤条件，没有配置时为空
+// 模板中的$user和$claims.<声明名>转换为保留变量引用，缓存的编译结果按请求重新绑定
+func (my *Context) Filter(className string) (*ast.Value, error) {
+	p, err := my.Permission(className)
+	if err != nil || p == nil || len(p.Filter) == 0 {
+		return nil, err
+	}
+	return templateValue(p.Filter), nil
+}
+
+// TemplateVariables 收集过滤模板中引用的$user和$claims.<声明名>保留变量
+func TemplateVariables(v interface{}, out map[string]bool) {
+	switch val := v.(type) {
+	case string:
+		if val == VAR_USER || strings.HasPrefix(val, VAR_CLAIMS) {
+			out[val] = true
+		}
+	case []interface{}:
+		for _, item := range val {
+			TemplateVariables(item, out)
+		}
+	case map[string]interface{}:
+		for _, item := range val {
+			TemplateVariables(item, out)
+		}
+	}
+}
+
+// templateValue 将配置中的过滤模板转换为GraphQL值
+func templateValue(v interface{}) *ast.Value {
+	switch val := v.(type) {
+	case nil:
+		return &ast.Value{Kind: ast.NullValue, Raw: "null"}
+	case string:
+		if val == VAR_USER || strings.HasPrefix(val, VAR_CLAIMS) {
+			return &ast.Value{Kind: ast.Variable, Raw: val}
+		}
+		return &ast.Value{Kind: ast.StringValue, Raw: val}
+	case bool:
+		return &ast.Value{Kind: ast.BooleanValue, Raw: strconv.FormatBool(val)}
+	case int:
+		return &ast.Value{Kind: ast.IntValue, Raw: strconv.Itoa(val)}
+	case int64:
+		return &ast.Value{Kind: ast.IntValue, Raw: strconv.FormatInt(val, 10)}
+	case float64:
+		if val == float64(int64(val)) {
+			return &ast.Value{Kind: ast.IntValue, Raw: strconv.FormatInt(int64(val), 10)}
+		}
+		return &ast.Value{Kind: ast.FloatValue, Raw: strconv.FormatFloat(val, 'f', -1, 64)}
+	case []interface{}:
+		list := &ast.Value{Kind: ast.ListValue}
+		for _, item := range val {
+			list.Children = append(list.Children, &ast.ChildValue{Value: templateValue(item)})
+		}
+		return list
+	case map[string]interface{}:
+		// 按键排序，保证同一模板生成的SQL稳定
+		object := &ast.Value{Kind: ast.ObjectValue}
+		for _, k := range utl.SortKeys(val) {
+			object.Children = append(object.Children, &ast.ChildValue{Name: k, Value: templateValue(val[k])})
+		}
+		return object
+	}
+	return &ast.Value{Kind: ast.StringValue, Raw: fmt.Sprint(v)}
+}
@@ -60,20 +60,20 @@ func (my *Dialect) BuildMutation(ctx *compiler.Context, set ast.SelectionSet) er
 
 	field, ok := set[0].(*ast.Field)
 	if !ok {
-		return fmt.Errorf("first selection must be a field")
+		return compiler.Errorf(set[0].GetPosition(), "first selection must be a field")
 	}
 
 	op := field.Definition.Name // 操作类型: insert/update/delete
 
 	switch op {
 	case "insert":
-		return my.buildInsert(ctx, field)
+		return compiler.WrapError(field.Position, my.buildInsert(ctx, field))
 	case "update":
-		return my.buildUpdate(ctx, field)
+		return compiler.WrapError(field.Position, my.buildUpdate(ctx, field))
 	case "delete":
-		return my.buildDelete(ctx, field)
+		return compiler.WrapError(field.Position, my.buildDelete(ctx, field))
 	default:
-		return fmt.Errorf("unsupported mutation operation: %s", op)
+		return compiler.Errorf(field.Position, "unsupported mutation operation: %s", op)
 	}
 }
 
@@ -93,29 +93,35 @@ func (my *Dialect) buildPagination(ctx *compiler.Context, args ast.ArgumentList)
 
 	// 处理分页参数
 	for _, arg := range args {
-		val, err := arg.Value.Value(nil)
+		// 只解析分页参数，避免其他参数中的变量使编译结果不可缓存
+		switch arg.Name {
+		case "limit", "offset", "after", "before":
+		default:
+			continue
+		}
+		val, err := ctx.Value(arg.Value)
 		if err != nil {
-			return fmt.Errorf("failed to get value for pagination argument %s: %w", arg.Name, err)
+			return compiler.Errorf(arg.Position, "failed to get value for pagination argument %s: %w", arg.Name, err)
 		}
 
 		switch arg.Name {
 		case "limit":
 			if intVal, ok := val.(int64); ok {
 				if intVal < 0 {
-					return fmt.Errorf("limit must be non-negative, got %d", intVal)
+					return compiler.Errorf(arg.Position, "limit must be non-negative, got %d", intVal)
 				}
 				limit = int(intVal)
 			} else {
-				return fmt.Errorf("limit must be an integer, got %T", val)
+				return compiler.Errorf(arg.Position, "limit must be an integer, got %T", val)
 			}
 		case "offset":
 			if intVal, ok := val.(int64); ok {
 				if intVal < 0 {
-					return fmt.Errorf("offset must be non-negative, got %d", intVal)
+					return compiler.Errorf(arg.Position, "offset must be non-negative, got %d", intVal)
 				}
 				offset = int(intVal)
 			} else {
-				return fmt.Errorf("offset must be an integer, got %T", val)
+				return compiler.Errorf(arg.Position, "offset must be an integer, got %T", val)
 			}
 		case "after":
 			after = val
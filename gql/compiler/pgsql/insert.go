@@ -2,11 +2,17 @@ package pgsql
 
 import (
 	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
 // buildInsert 构建INSERT语句
 func (my *Dialect) buildInsert(ctx *compiler.Context, field *ast.Field) error {
+	// 校验当前角色的操作权限
+	if err := ctx.Allow(field.Position, field.Name, protocol.OP_CREATE); err != nil {
+		return err
+	}
+
 	ctx.SpaceAfter("INSERT INTO").
 		Quote(field.Name).
 		SpaceBefore("(")
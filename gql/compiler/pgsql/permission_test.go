@@ -0,0 +1,90 @@
+package pgsql
+
+import (
+	"testing"
+
+	"github.com/ichaly/ideabase/gql"
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// stubHoster 只提供类查找的元数据
+type stubHoster map[string]*protocol.Class
+
+func (my stubHoster) PutNode(name string, node *protocol.Class) error { my[name] = node; return nil }
+func (my stubHoster) GetNode(name string) (*protocol.Class, bool)     { c, ok := my[name]; return c, ok }
+func (my stubHoster) SetVersion(string)                               {}
+
+func TestPermission(t *testing.T) {
+	dialect := &Dialect{}
+	posts := &protocol.Class{Name: "Post", Table: "posts", Fields: map[string]*protocol.Field{}}
+	for _, name := range []string{"id", "title", "content"} {
+		posts.AddField(&protocol.Field{Name: name, Column: name})
+	}
+	posts.Permissions = map[string]*protocol.Permission{
+		"user": {
+			Operations:    []string{protocol.OP_QUERY, protocol.OP_UPDATE},
+			Filter:        map[string]interface{}{"userId": map[string]interface{}{"eq": "$user"}},
+			ExcludeFields: []string{"content"},
+		},
+	}
+	hoster := stubHoster{"posts": posts}
+	newContext := func(role string) *compiler.Context {
+		return compiler.NewContext(hoster, dialect.Quotation(), map[string]interface{}{
+			compiler.VAR_ROLE: role,
+			compiler.VAR_USER: uint64(7),
+		})
+	}
+	newField := func() *ast.Field {
+		return &ast.Field{
+			Name: "posts",
+			Arguments: ast.ArgumentList{
+				{Name: gql.ID, Value: &ast.Value{Kind: ast.IntValue, Raw: "1"}},
+				{Name: "update", Value: &ast.Value{Kind: ast.ObjectValue, Children: ast.ChildValueList{
+					{Name: "title", Value: &ast.Value{Kind: ast.StringValue, Raw: "a"}},
+				}}},
+			},
+		}
+	}
+
+	t.Run("注入行过滤条件并绑定当前用户", func(t *testing.T) {
+		ctx := newContext("user")
+		defer ctx.Release()
+		require.NoError(t, dialect.buildUpdate(ctx, newField()))
+		assert.Contains(t, formatSQL(ctx.String()), formatSQL(`WHERE ("id" = $2 AND "userId" = $3)`))
+		assert.Equal(t, []any{"a", int64(1), uint64(7)}, ctx.Args())
+		require.Len(t, ctx.Slots(), 1, "当前用户作为参数槽，缓存的计划按请求重新绑定")
+		assert.Equal(t, 2, ctx.Slots()[0].Index)
+	})
+
+	t.Run("拒绝未授权的操作", func(t *testing.T) {
+		ctx := newContext("user")
+		defer ctx.Release()
+		assert.ErrorIs(t, dialect.buildDelete(ctx, newField()), compiler.ErrForbidden)
+	})
+
+	t.Run("拒绝未配置的角色", func(t *testing.T) {
+		ctx := newContext("guest")
+		defer ctx.Release()
+		assert.ErrorIs(t, dialect.buildUpdate(ctx, newField()), compiler.ErrForbidden)
+	})
+
+	t.Run("隐藏禁止访问的字段", func(t *testing.T) {
+		ctx := newContext("user")
+		defer ctx.Release()
+		_, ok := ctx.FindField("posts", "content")
+		assert.False(t, ok)
+		_, ok = ctx.FindField("posts", "title")
+		assert.True(t, ok)
+	})
+
+	t.Run("未启用权限时不限制", func(t *testing.T) {
+		ctx := newContext("")
+		defer ctx.Release()
+		require.NoError(t, dialect.buildDelete(ctx, newField()))
+		assert.Equal(t, []any{int64(1)}, ctx.Args())
+	})
+}
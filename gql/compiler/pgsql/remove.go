@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -15,11 +16,20 @@ func (my *Dialect) buildDelete(ctx *compiler.Context, field *ast.Field) error {
 		return fmt.Errorf("table name is required")
 	}
 
+	// 校验当前角色的操作权限
+	if err := ctx.Allow(field.Position, field.Name, protocol.OP_DELETE); err != nil {
+		return err
+	}
+	filter, err := ctx.Filter(field.Name)
+	if err != nil {
+		return err
+	}
+
 	// 开始构建DELETE语句
 	ctx.SpaceAfter("DELETE FROM").Quote(field.Name)
 
-	// 处理WHERE条件
-	if err := my.buildWhere(ctx, field.Arguments); err != nil {
+	// 处理WHERE条件，合并权限规则的行过滤条件
+	if err := my.buildWhereWithAlias(ctx, field.Arguments, "", filter); err != nil {
 		return fmt.Errorf("failed to build WHERE clause: %w", err)
 	}
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ichaly/ideabase/gql"
 	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -21,8 +22,7 @@ func (my *Dialect) BuildQuery(ctx *compiler.Context, set ast.SelectionSet) error
 		return err
 	}
 
-	my.buildJson(ctx, set)
-	return nil
+	return my.buildJson(ctx, set)
 }
 
 func (my *Dialect) buildRoot(ctx *compiler.Context, set ast.SelectionSet) error {
@@ -30,7 +30,7 @@ func (my *Dialect) buildRoot(ctx *compiler.Context, set ast.SelectionSet) error
 	for i, s := range set {
 		field, ok := s.(*ast.Field)
 		if !ok {
-			return fmt.Errorf("selection must be a field")
+			return compiler.Errorf(s.GetPosition(), "selection must be a field")
 		}
 		if i != 0 {
 			ctx.SpaceAfter(`,`)
@@ -41,7 +41,7 @@ func (my *Dialect) buildRoot(ctx *compiler.Context, set ast.SelectionSet) error
 	return nil
 }
 
-func (my *Dialect) buildJson(ctx *compiler.Context, set ast.SelectionSet) {
+func (my *Dialect) buildJson(ctx *compiler.Context, set ast.SelectionSet) error {
 	i := 0 // 关系字段的索引计数器
 
 	for _, s := range set {
@@ -75,10 +75,14 @@ func (my *Dialect) buildJson(ctx *compiler.Context, set ast.SelectionSet) {
 
 		ctx.Write(`) AS "json" FROM (`)
 
-		my.buildSelect(ctx, field, i, "0")
+		if err := my.buildSelect(ctx, field, i, "0"); err != nil {
+			return err
+		}
 		// 递归处理嵌套的关系字段
 		if len(field.SelectionSet) > 0 {
-			my.buildJson(ctx, field.SelectionSet)
+			if err := my.buildJson(ctx, field.SelectionSet); err != nil {
+				return err
+			}
 		}
 
 		ctx.SpaceAfter(`) AS`).Quote(`__sr_`, i)
@@ -86,12 +90,23 @@ func (my *Dialect) buildJson(ctx *compiler.Context, set ast.SelectionSet) {
 
 		i++ // 递增关系字段索引
 	}
+	return nil
 }
 
-func (my *Dialect) buildSelect(ctx *compiler.Context, field *ast.Field, index int, parent string) {
-	table, ok := ctx.TableName(field.Definition.Type.Name())
+// 参数错误附加字段位置，便于映射回GraphQL文档
+// 当前角色的权限规则在这里注入，嵌套的关联查询同样经过此处
+func (my *Dialect) buildSelect(ctx *compiler.Context, field *ast.Field, index int, parent string) error {
+	className := field.Definition.Type.Name()
+	table, ok := ctx.TableName(className)
 	if !ok {
-		return
+		return nil
+	}
+	if err := ctx.Allow(field.Position, className, protocol.OP_QUERY); err != nil {
+		return err
+	}
+	filter, err := ctx.Filter(className)
+	if err != nil {
+		return compiler.WrapError(field.Position, err)
 	}
 
 	alias := strings.Join([]string{table, parent, strconv.Itoa(index)}, "_")
@@ -106,13 +121,17 @@ func (my *Dialect) buildSelect(ctx *compiler.Context, field *ast.Field, index in
 	ctx.Space("FROM").Write(table).Space(`) AS`).Quote(alias)
 
 	// 统一处理WHERE条件（包括id参数转换） - 调用where.go中的方法
-	_ = my.buildWhereWithAlias(ctx, field.Arguments, alias)
+	if err := my.buildWhereWithAlias(ctx, field.Arguments, alias, filter); err != nil {
+		return compiler.WrapError(field.Position, err)
+	}
 
 	// 处理排序
-	_ = my.buildOrderBy(ctx, field.Arguments)
+	if err := my.buildOrderBy(ctx, field.Arguments); err != nil {
+		return compiler.WrapError(field.Position, err)
+	}
 
 	// 处理分页（所有查询都可能需要分页）
-	_ = my.buildPagination(ctx, field.Arguments)
+	return compiler.WrapError(field.Position, my.buildPagination(ctx, field.Arguments))
 }
 
 // 构建选择字段
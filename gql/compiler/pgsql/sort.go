@@ -68,7 +68,7 @@ func (my *Dialect) buildLegacyOrderBy(ctx *compiler.Context, args ast.ArgumentLi
 
 			ctx.Space("").Quote(child.Name)
 
-			value, err := child.Value.Value(nil)
+			value, err := ctx.Value(child.Value)
 			if err != nil {
 				return fmt.Errorf("failed to get value for order by field %s: %w", child.Name, err)
 			}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -15,6 +16,15 @@ func (my *Dialect) buildUpdate(ctx *compiler.Context, field *ast.Field) error {
 		return fmt.Errorf("table name is required")
 	}
 
+	// 校验当前角色的操作权限
+	if err := ctx.Allow(field.Position, field.Name, protocol.OP_UPDATE); err != nil {
+		return err
+	}
+	filter, err := ctx.Filter(field.Name)
+	if err != nil {
+		return err
+	}
+
 	// 获取更新参数
 	update := field.Arguments.ForName("update")
 	if update == nil {
@@ -43,16 +53,15 @@ func (my *Dialect) buildUpdate(ctx *compiler.Context, field *ast.Field) error {
 			Write(" = ")
 
 		// 添加参数占位符
-		value, err := child.Value.Value(nil)
+		index, err := ctx.Param(child.Value)
 		if err != nil {
 			return fmt.Errorf("failed to get value for field %s: %w", child.Name, err)
 		}
-		ctx.Write(my.Placeholder(len(ctx.Args()) + 1))
-		ctx.AddParam(value)
+		ctx.Write(my.Placeholder(index))
 	}
 
-	// 处理WHERE条件
-	if err := my.buildWhere(ctx, field.Arguments); err != nil {
+	// 处理WHERE条件，合并权限规则的行过滤条件
+	if err := my.buildWhereWithAlias(ctx, field.Arguments, "", filter); err != nil {
 		return fmt.Errorf("failed to build WHERE clause: %w", err)
 	}
 
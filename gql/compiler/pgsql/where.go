@@ -16,8 +16,14 @@ func (my *Dialect) buildWhere(ctx *compiler.Context, args ast.ArgumentList) erro
 }
 
 // buildWhereWithAlias 构建WHERE子句，支持表别名和id参数转换
-func (my *Dialect) buildWhereWithAlias(ctx *compiler.Context, args ast.ArgumentList, alias string) error {
+// filters为权限规则注入的行过滤条件，与参数中的条件用AND连接
+func (my *Dialect) buildWhereWithAlias(ctx *compiler.Context, args ast.ArgumentList, alias string, filters ...*ast.Value) error {
 	conditions := my.collectConditions(args)
+	for _, filter := range filters {
+		if filter != nil {
+			conditions = append(conditions, filter)
+		}
+	}
 	if len(conditions) == 0 {
 		return nil
 	}
@@ -156,7 +162,8 @@ func (my *Dialect) buildChildValueWithAlias(ctx *compiler.Context, child *ast.Ch
 	case gql.NOT:
 		return my.buildNotOperatorWithAlias(ctx, child, alias)
 	default:
-		return my.buildFieldConditionWithAlias(ctx, child, alias)
+		// 字段条件的错误定位到对应的过滤字段
+		return compiler.WrapError(child.Position, my.buildFieldConditionWithAlias(ctx, child, alias))
 	}
 }
 
@@ -357,7 +364,7 @@ func (my *Dialect) buildInValue(ctx *compiler.Context, value *ast.Value) error {
 
 // buildIsValue 构建IS操作符的值（NULL检查）
 func (my *Dialect) buildIsValue(ctx *compiler.Context, value *ast.Value) error {
-	val, err := value.Value(nil)
+	val, err := ctx.Value(value)
 	if err != nil {
 		return err
 	}
@@ -376,14 +383,11 @@ func (my *Dialect) buildIsValue(ctx *compiler.Context, value *ast.Value) error {
 
 // buildParam 构建参数值
 func (my *Dialect) buildParam(ctx *compiler.Context, value *ast.Value) error {
-	val, err := value.Value(nil)
+	index, err := ctx.Param(value)
 	if err != nil {
 		return fmt.Errorf("failed to get parameter value: %w", err)
 	}
-
-	placeholder := my.Placeholder(len(ctx.Args()) + 1)
-	ctx.Write(placeholder)
-	ctx.AddParam(val)
+	ctx.Write(my.Placeholder(index))
 
 	return nil
 }
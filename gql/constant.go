@@ -63,6 +63,7 @@ const (
 )
 
 const (
+	ROOT      = "__root"
 	TOTAL     = "total"
 	ITEMS     = "items"
 	PAGE_INFO = "pageInfo"
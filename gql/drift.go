@@ -0,0 +1,228 @@
+package gql
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"gorm.io/gorm"
+)
+
+// 元数据快照来源
+const (
+	SnapshotFile     = "file"   // 开发模式保存的元数据文件
+	SnapshotDatabase = "db"     // 数据库的实时表结构
+	SnapshotEntity   = "entity" // 注册的GORM实体
+)
+
+// ChangeKind 元数据差异的类型
+type ChangeKind string
+
+const (
+	ClassAdded         ChangeKind = "ClassAdded"
+	ClassRemoved       ChangeKind = "ClassRemoved"
+	FieldAdded         ChangeKind = "FieldAdded"
+	FieldRemoved       ChangeKind = "FieldRemoved"
+	TypeChanged        ChangeKind = "TypeChanged"
+	NullabilityChanged ChangeKind = "NullabilityChanged"
+	RelationChanged    ChangeKind = "RelationChanged"
+)
+
+// Change 两份元数据之间的一处差异，Breaking表示会破坏已有的GraphQL客户端
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Class    string     `json:"class"`
+	Field    string     `json:"field,omitempty"`
+	From     string     `json:"from,omitempty"`
+	To       string     `json:"to,omitempty"`
+	Breaking bool       `json:"breaking"`
+}
+
+func (my Change) String() string {
+	level := "safe"
+	if my.Breaking {
+		level = "breaking"
+	}
+	path := my.Class
+	if my.Field != "" {
+		path += "." + my.Field
+	}
+	if my.From != "" || my.To != "" {
+		return fmt.Sprintf("[%s] %s %s: %s -> %s", level, my.Kind, path, my.From, my.To)
+	}
+	return fmt.Sprintf("[%s] %s %s", level, my.Kind, path)
+}
+
+// HasBreaking 是否包含破坏性变更
+func HasBreaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSnapshot 从单一来源构建元数据快照，用于对比不同来源之间的差异
+// 与NewMetadata相同地规范化命名并生成关系字段，但不区分运行模式、不写入元数据文件，也不做严格校验
+func NewSnapshot(k *std.Konfig, d *gorm.DB, source string, entities ...interface{}) (*Metadata, error) {
+	cfg, err := newMetadataConfig(k)
+	if err != nil {
+		return nil, err
+	}
+	var loader protocol.Loader
+	switch source {
+	case SnapshotFile:
+		loader = metadata.NewFileLoader(cfg)
+	case SnapshotEntity:
+		loader = metadata.NewGormLoader(cfg, d, entities...)
+	case SnapshotDatabase:
+		if d == nil {
+			return nil, fmt.Errorf("快照来源%s需要数据库连接", source)
+		}
+		switch d.Dialector.Name() {
+		case "postgres":
+			loader = metadata.NewPgsqlLoader(cfg, d)
+		case "mysql":
+			loader = metadata.NewMysqlLoader(cfg, d)
+		default:
+			return nil, fmt.Errorf("不支持读取%s数据库的表结构", d.Dialector.Name())
+		}
+	default:
+		return nil, fmt.Errorf("未知的快照来源: %s", source)
+	}
+
+	my := &Metadata{k: k, db: d, cfg: cfg, Nodes: make(map[string]*protocol.Class)}
+	if err = loader.Load(my); err != nil {
+		return nil, err
+	}
+	if err = my.normalize(); err != nil {
+		return nil, err
+	}
+	my.processRelations()
+	return my, nil
+}
+
+// Diff 对比两份元数据，按类名和字段名排序返回差异
+//
+// 变更分类按自动生成的GraphQL API判断：
+//   - 新增类、可空字段和关系为安全变更；删除类、字段和关系，修改类型和关系为破坏性变更
+//   - 新增非空的实体字段会成为创建时的必填输入，为破坏性变更
+//   - 字段改为可空后查询结果可能为null，为破坏性变更；改为非空后成为必填输入，只用于查询的关系字段为安全变更
+func Diff(from, to *Metadata) []Change {
+	var changes []Change
+	old, cur := from.classes(), to.classes()
+	for _, name := range sortedKeys(old, cur) {
+		a, b := old[name], cur[name]
+		switch {
+		case b == nil:
+			changes = append(changes, Change{Kind: ClassRemoved, Class: name, Breaking: true})
+		case a == nil:
+			changes = append(changes, Change{Kind: ClassAdded, Class: name})
+		default:
+			changes = append(changes, diffClass(name, a, b)...)
+		}
+	}
+	return changes
+}
+
+// diffClass 对比同名类的字段
+func diffClass(class string, a, b *protocol.Class) []Change {
+	var changes []Change
+	old, cur := primaryFields(a), primaryFields(b)
+	for _, name := range sortedKeys(old, cur) {
+		x, y := old[name], cur[name]
+		change := Change{Class: class, Field: name}
+		switch {
+		case y == nil:
+			change.Kind, change.Breaking = FieldRemoved, true
+			changes = append(changes, change)
+			continue
+		case x == nil:
+			change.Kind = FieldAdded
+			change.To = fieldType(y)
+			change.Breaking = !y.Nullable && !y.Virtual && !y.IsPrimary
+			changes = append(changes, change)
+			continue
+		}
+		if from, to := fieldType(x), fieldType(y); from != to {
+			change.Kind, change.From, change.To, change.Breaking = TypeChanged, from, to, true
+			changes = append(changes, change)
+		}
+		if x.Nullable != y.Nullable {
+			change.Kind = NullabilityChanged
+			change.From, change.To = nullability(x), nullability(y)
+			change.Breaking = y.Nullable || !y.Virtual
+			changes = append(changes, change)
+		}
+		if from, to := relationKey(x.Relation), relationKey(y.Relation); from != to {
+			change.Kind, change.From, change.To = RelationChanged, from, to
+			change.Breaking = from != ""
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// classes 以类名索引的主节点，跳过表名等别名索引
+func (my *Metadata) classes() map[string]*protocol.Class {
+	result := make(map[string]*protocol.Class)
+	if my == nil {
+		return result
+	}
+	for key, class := range my.Nodes {
+		if key == class.Name {
+			result[key] = class
+		}
+	}
+	return result
+}
+
+// primaryFields 以字段名索引的字段，跳过列名索引
+func primaryFields(class *protocol.Class) map[string]*protocol.Field {
+	result := make(map[string]*protocol.Field)
+	for key, field := range class.Fields {
+		if key == field.Name {
+			result[key] = field
+		}
+	}
+	return result
+}
+
+func sortedKeys[T any](a, b map[string]T) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]T{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fieldType(f *protocol.Field) string {
+	if f.IsList {
+		return "[" + f.Type + "]"
+	}
+	return f.Type
+}
+
+func nullability(f *protocol.Field) string {
+	if f.Nullable {
+		return "nullable"
+	}
+	return "non-null"
+}
+
+func relationKey(r *protocol.Relation) string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s.%s", r.Type, r.TargetClass, r.TargetFiled)
+}
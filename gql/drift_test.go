@@ -0,0 +1,92 @@
+package gql
+
+import (
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// driftUser 修改后的用户实体：用户名改为可空，新增邮箱和昵称，移除角色关联
+type driftUser struct {
+	std.Entity
+	Name     *string       `gorm:"size:64;unique;comment:用户名"`
+	Email    string        `gorm:"size:128;not null"`
+	Nickname *string       `gorm:"size:64"`
+	Articles []gormArticle `gorm:"foreignKey:AuthorId"`
+}
+
+func (driftUser) TableName() string { return "sys_users" }
+
+func TestDiff(t *testing.T) {
+	k, err := std.NewKonfig()
+	require.NoError(t, err)
+	k.Set("mode", "test")
+	k.Set("app.root", t.TempDir())
+	k.Set("metadata.table-prefix", []string{"sys_"})
+
+	before, err := NewSnapshot(k, nil, SnapshotEntity, gormUser{}, &gormArticle{}, gormRole{})
+	require.NoError(t, err)
+	// 与开发模式相同，在加载后、规范化前保存元数据文件
+	cfg, err := newMetadataConfig(k)
+	require.NoError(t, err)
+	saved := &Metadata{Nodes: make(map[string]*protocol.Class)}
+	require.NoError(t, metadata.NewGormLoader(cfg, nil, gormUser{}, &gormArticle{}, gormRole{}).Load(saved))
+	require.NoError(t, saved.saveToFile(metadata.ResolveMetadataPath(cfg)))
+
+	file, err := NewSnapshot(k, nil, SnapshotFile)
+	require.NoError(t, err)
+	assert.Empty(t, Diff(before, file), "保存的文件与实体一致")
+
+	after, err := NewSnapshot(k, nil, SnapshotEntity, driftUser{}, &gormArticle{})
+	require.NoError(t, err)
+	changes := Diff(file, after)
+	assert.True(t, HasBreaking(changes))
+	assert.Contains(t, changes, Change{Kind: ClassRemoved, Class: "Role", Breaking: true})
+	assert.Contains(t, changes, Change{Kind: FieldRemoved, Class: "User", Field: "roles", Breaking: true})
+	assert.Contains(t, changes, Change{Kind: FieldAdded, Class: "User", Field: "email", To: "varchar", Breaking: true}, "新增非空字段为必填输入")
+	assert.Contains(t, changes, Change{Kind: FieldAdded, Class: "User", Field: "nickname", To: "varchar"})
+	assert.Contains(t, changes, Change{Kind: NullabilityChanged, Class: "User", Field: "name", From: "non-null", To: "nullable", Breaking: true})
+	assert.Contains(t, changes, Change{Kind: RelationChanged, Class: "User", Field: "id", From: "ManyToMany Role.id", Breaking: true})
+	assert.Equal(t, "[breaking] ClassRemoved Role", changes[0].String())
+
+	t.Run("元数据文件保留成本权重", func(t *testing.T) {
+		for _, class := range saved.Nodes {
+			class.Cost = 5
+		}
+		require.NoError(t, saved.saveToFile(metadata.ResolveMetadataPath(cfg)))
+		file, err := NewSnapshot(k, nil, SnapshotFile)
+		require.NoError(t, err)
+		require.Contains(t, file.Nodes, "User")
+		assert.Equal(t, 5, file.Nodes["User"].Cost)
+	})
+
+	t.Run("类型与关系变更", func(t *testing.T) {
+		node := func(fields ...*protocol.Field) *Metadata {
+			class := &protocol.Class{Name: "Post", Table: "posts", Fields: map[string]*protocol.Field{}}
+			for _, f := range fields {
+				class.AddField(f)
+			}
+			return &Metadata{Nodes: map[string]*protocol.Class{"Post": class, "posts": class}}
+		}
+		from := node(
+			&protocol.Field{Name: "views", Column: "view_count", Type: "Int"},
+			&protocol.Field{Name: "authorId", Type: "ID"},
+			&protocol.Field{Name: "author", Type: "User", Virtual: true, Nullable: true},
+		)
+		to := node(
+			&protocol.Field{Name: "views", Column: "view_count", Type: "Int", IsList: true},
+			&protocol.Field{Name: "authorId", Type: "ID", Relation: &protocol.Relation{Type: protocol.MANY_TO_ONE, TargetClass: "User", TargetFiled: "id"}},
+			&protocol.Field{Name: "author", Type: "User", Virtual: true},
+		)
+		assert.Equal(t, []Change{
+			{Kind: NullabilityChanged, Class: "Post", Field: "author", From: "nullable", To: "non-null"},
+			{Kind: RelationChanged, Class: "Post", Field: "authorId", To: "ManyToOne User.id"},
+			{Kind: TypeChanged, Class: "Post", Field: "views", From: "Int", To: "[Int]", Breaking: true},
+		}, Diff(from, to), "列名索引不重复比较，只用于查询的关系字段改为非空、新增关系为安全变更")
+		assert.False(t, HasBreaking(Diff(to, to)))
+	})
+}
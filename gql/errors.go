@@ -0,0 +1,215 @@
+package gql
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/utl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// 执行错误码，写入错误的extensions.code
+const (
+	CODE_GRAPHQL_PARSE_FAILED      = "GRAPHQL_PARSE_FAILED"
+	CODE_GRAPHQL_VALIDATION_FAILED = "GRAPHQL_VALIDATION_FAILED"
+	CODE_COMPILE_FAILED            = "COMPILE_FAILED"
+	CODE_UNIQUE_VIOLATION          = "UNIQUE_VIOLATION"
+	CODE_FOREIGN_KEY_VIOLATION     = "FOREIGN_KEY_VIOLATION"
+	CODE_NOT_NULL_VIOLATION        = "NOT_NULL_VIOLATION"
+	CODE_CHECK_VIOLATION           = "CHECK_VIOLATION"
+)
+
+var (
+	// PostgreSQL唯一键和外键错误详情，如: Key (email)=(a@b.c) already exists.
+	pgKeyPattern = regexp.MustCompile(`Key \(([^,)]+)`)
+	// MySQL唯一键错误，如: Duplicate entry 'a@b.c' for key 'users.email'
+	mysqlKeyPattern = regexp.MustCompile(`for key '(?:[^'.]+\.)?([^']+)'`)
+	// MySQL外键错误，如: CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES ...
+	mysqlForeignPattern = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`")
+	// MySQL非空错误，如: Column 'name' cannot be null
+	mysqlColumnPattern = regexp.MustCompile(`Column '([^']+)'`)
+	// MySQL检查约束错误，如: Check constraint 'age_positive' is violated.
+	mysqlCheckPattern = regexp.MustCompile(`Check constraint '([^']+)'`)
+)
+
+// violation 数据库约束错误的解析结果
+type violation struct {
+	code       string
+	column     string
+	constraint string
+}
+
+// parseViolation 将PostgreSQL和MySQL的唯一键、外键、非空和检查约束错误解析为统一的错误码
+func parseViolation(err error) (*violation, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		v := &violation{column: pgErr.ColumnName, constraint: pgErr.ConstraintName}
+		switch pgErr.Code {
+		case "23505":
+			v.code = CODE_UNIQUE_VIOLATION
+		case "23503":
+			v.code = CODE_FOREIGN_KEY_VIOLATION
+		case "23502":
+			v.code = CODE_NOT_NULL_VIOLATION
+		case "23514":
+			v.code = CODE_CHECK_VIOLATION
+		default:
+			return nil, false
+		}
+		if m := pgKeyPattern.FindStringSubmatch(pgErr.Detail); v.column == "" && m != nil {
+			v.column = strings.Trim(m[1], `"`)
+		}
+		return v, true
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		v := &violation{}
+		switch myErr.Number {
+		case 1062:
+			// 单列唯一索引默认以列名命名
+			v.code = CODE_UNIQUE_VIOLATION
+			if m := mysqlKeyPattern.FindStringSubmatch(myErr.Message); m != nil {
+				v.constraint, v.column = m[1], m[1]
+			}
+		case 1451, 1452:
+			v.code = CODE_FOREIGN_KEY_VIOLATION
+			if m := mysqlForeignPattern.FindStringSubmatch(myErr.Message); m != nil {
+				v.constraint, v.column = m[1], m[2]
+			}
+		case 1048:
+			v.code = CODE_NOT_NULL_VIOLATION
+			if m := mysqlColumnPattern.FindStringSubmatch(myErr.Message); m != nil {
+				v.column = m[1]
+			}
+		case 3819:
+			v.code = CODE_CHECK_VIOLATION
+			if m := mysqlCheckPattern.FindStringSubmatch(myErr.Message); m != nil {
+				v.constraint = m[1]
+			}
+		default:
+			return nil, false
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// documentErrors 为解析和校验错误补充错误码，保留gqlparser给出的位置
+func documentErrors(err error) gqlerror.List {
+	var list gqlerror.List
+	if !errors.As(err, &list) {
+		return gqlerror.List{gqlerror.Wrap(err)}
+	}
+	for _, e := range list {
+		code := CODE_GRAPHQL_PARSE_FAILED
+		if e.Rule != "" {
+			code = CODE_GRAPHQL_VALIDATION_FAILED
+		}
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]interface{})
+		}
+		e.Extensions["code"] = code
+	}
+	return list
+}
+
+// fieldError 将根字段执行失败的错误转换为带路径和位置的GraphQL错误
+// 取消和超时错误单独标记，编译错误定位到出错的字段或参数，数据库约束错误定位到对应的输入字段
+func (my *Executor) fieldError(field *ast.Field, err error) *gqlerror.Error {
+	e := &gqlerror.Error{
+		Err:       err,
+		Message:   err.Error(),
+		Path:      ast.Path{ast.PathName(field.Alias)},
+		Locations: locations(field.Position),
+	}
+
+	if code := cancelCode(err); code != "" {
+		e.Extensions = map[string]interface{}{"code": code}
+		return e
+	}
+	if forbidden(err) {
+		e.Extensions = map[string]interface{}{"code": CODE_FORBIDDEN}
+		return e
+	}
+
+	var ce *compiler.Error
+	if errors.As(err, &ce) {
+		e.Extensions = map[string]interface{}{"code": CODE_COMPILE_FAILED}
+		if ce.Position != nil {
+			e.Locations = locations(ce.Position)
+		}
+		return e
+	}
+
+	v, ok := parseViolation(err)
+	if !ok {
+		return e
+	}
+	e.Extensions = map[string]interface{}{"code": v.code}
+	if v.constraint != "" {
+		e.Extensions["constraint"] = v.constraint
+	}
+	if name, pos := my.inputField(field, v.column); name != "" {
+		e.Extensions["field"] = name
+		if pos != nil {
+			e.Locations = locations(pos)
+		}
+	}
+	return e
+}
+
+// inputField 根据列名查找字段名，变更的输入对象中包含该字段时返回其在文档中的位置
+func (my *Executor) inputField(field *ast.Field, column string) (string, *ast.Position) {
+	class := my.fieldClass(field)
+	if class == nil || column == "" {
+		return "", nil
+	}
+	for _, name := range utl.SortKeys(class.Fields) {
+		if class.Fields[name].Column != column {
+			continue
+		}
+		arg := field.Arguments.ForName(INPUT)
+		if arg == nil || arg.Value == nil {
+			return name, nil
+		}
+		if value := arg.Value.Children.ForName(name); value != nil {
+			return name, value.Position
+		}
+		return name, arg.Position
+	}
+	return "", nil
+}
+
+// fieldClass 查找根字段对应的类，变更字段按create/update/delete前缀解析
+func (my *Executor) fieldClass(field *ast.Field) *protocol.Class {
+	for _, prefix := range []string{CREATE, UPDATE, DELETE} {
+		if name, ok := strings.CutPrefix(field.Name, prefix); ok {
+			if class, ok := my.metadata.Nodes[name]; ok {
+				return class
+			}
+		}
+	}
+	if field.Definition == nil {
+		return nil
+	}
+	typeName := field.Definition.Type.Name()
+	if class, ok := my.metadata.Nodes[strings.TrimSuffix(typeName, SUFFIX_RESULT)]; ok {
+		return class
+	}
+	return nil
+}
+
+// locations 将AST位置转换为错误位置
+func locations(pos *ast.Position) []gqlerror.Location {
+	if pos == nil {
+		return nil
+	}
+	return []gqlerror.Location{{Line: pos.Line, Column: pos.Column}}
+}
@@ -0,0 +1,224 @@
+package gql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeConnector 按SQL文本返回根结果JSON或错误的测试数据库，记录执行的非查询语句
+type fakeConnector struct {
+	handle func(ctx context.Context, query string) (string, error)
+	execs  []string
+}
+
+func (my *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{my}, nil }
+func (my *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ c *fakeConnector }
+
+func (my *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{my.c, query}, nil }
+func (my *fakeConn) Close() error                              { return nil }
+func (my *fakeConn) Begin() (driver.Tx, error)                 { return my, nil }
+func (my *fakeConn) Commit() error                             { return nil }
+func (my *fakeConn) Rollback() error                           { return nil }
+
+type fakeStmt struct {
+	c     *fakeConnector
+	query string
+}
+
+func (my *fakeStmt) Close() error  { return nil }
+func (my *fakeStmt) NumInput() int { return -1 }
+func (my *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	my.c.execs = append(my.c.execs, my.query)
+	return driver.RowsAffected(0), nil
+}
+func (my *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return my.QueryContext(context.Background(), nil)
+}
+func (my *fakeStmt) QueryContext(ctx context.Context, _ []driver.NamedValue) (driver.Rows, error) {
+	root, err := my.c.handle(ctx, my.query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{root: root}, nil
+}
+
+type fakeRows struct {
+	root string
+	done bool
+}
+
+func (my *fakeRows) Columns() []string { return []string{ROOT} }
+func (my *fakeRows) Close() error      { return nil }
+func (my *fakeRows) Next(dest []driver.Value) error {
+	if my.done {
+		return io.EOF
+	}
+	dest[0], my.done = my.root, true
+	return nil
+}
+
+// newFakeDatabase 创建使用测试连接的数据库
+func newFakeDatabase(t *testing.T, handle func(ctx context.Context, query string) (string, error)) (*gorm.DB, *fakeConnector) {
+	connector := &fakeConnector{handle: handle}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	require.NoError(t, err)
+	return db, connector
+}
+
+func TestErrorsViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *violation
+	}{
+		{
+			name: "PostgreSQL唯一键",
+			err:  &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key", Detail: "Key (email)=(a@b.c) already exists."},
+			want: &violation{code: CODE_UNIQUE_VIOLATION, column: "email", constraint: "users_email_key"},
+		},
+		{
+			name: "PostgreSQL外键",
+			err:  fmt.Errorf("执行失败: %w", &pgconn.PgError{Code: "23503", ConstraintName: "posts_user_id_fkey", Detail: `Key (user_id)=(9) is not present in table "users".`}),
+			want: &violation{code: CODE_FOREIGN_KEY_VIOLATION, column: "user_id", constraint: "posts_user_id_fkey"},
+		},
+		{
+			name: "PostgreSQL非空",
+			err:  &pgconn.PgError{Code: "23502", ColumnName: "name"},
+			want: &violation{code: CODE_NOT_NULL_VIOLATION, column: "name"},
+		},
+		{
+			name: "PostgreSQL检查约束",
+			err:  &pgconn.PgError{Code: "23514", ConstraintName: "age_positive"},
+			want: &violation{code: CODE_CHECK_VIOLATION, constraint: "age_positive"},
+		},
+		{
+			name: "MySQL唯一键",
+			err:  &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'users.email'"},
+			want: &violation{code: CODE_UNIQUE_VIOLATION, column: "email", constraint: "email"},
+		},
+		{
+			name: "MySQL外键",
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`db`.`posts`, CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			want: &violation{code: CODE_FOREIGN_KEY_VIOLATION, column: "user_id", constraint: "fk_user"},
+		},
+		{
+			name: "MySQL非空",
+			err:  &mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"},
+			want: &violation{code: CODE_NOT_NULL_VIOLATION, column: "name"},
+		},
+		{
+			name: "MySQL检查约束",
+			err:  &mysql.MySQLError{Number: 3819, Message: "Check constraint 'age_positive' is violated."},
+			want: &violation{code: CODE_CHECK_VIOLATION, constraint: "age_positive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseViolation(tt.err)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := parseViolation(&pgconn.PgError{Code: "42P01"})
+	assert.False(t, ok, "非约束错误")
+	_, ok = parseViolation(errors.New("connection refused"))
+	assert.False(t, ok)
+}
+
+func TestErrorsPartialData(t *testing.T) {
+	var calls int
+	executor := newCountingExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(_ context.Context, query string) (string, error) {
+		calls++
+		switch query {
+		case `SELECT "users"`:
+			return `{"users":{"total":2}}`, nil
+		case `SELECT "createUser"`:
+			return "", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key", Detail: "Key (email)=(dup) already exists."}
+		}
+		return "", &pgconn.PgError{Code: "23514", ConstraintName: "posts_check", Message: "违反检查约束"}
+	})
+	execute := func(query string) gqlReply {
+		return executor.Execute(context.Background(), query, nil, "")
+	}
+	codeOf := func(e *gqlerror.Error) interface{} {
+		return e.Extensions["code"]
+	}
+
+	t.Run("独立的根字段返回部分结果", func(t *testing.T) {
+		r := execute("{\n  users { total }\n  list: posts { total }\n}")
+		assert.Equal(t, map[string]interface{}{"total": float64(2)}, r.Data["users"])
+		assert.Contains(t, r.Data, "list")
+		assert.Nil(t, r.Data["list"])
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, ast.Path{ast.PathName("list")}, r.Errors[0].Path)
+		assert.Equal(t, []gqlerror.Location{{Line: 3, Column: 3}}, r.Errors[0].Locations)
+		assert.Equal(t, CODE_CHECK_VIOLATION, codeOf(r.Errors[0]))
+		assert.Equal(t, "posts_check", r.Errors[0].Extensions["constraint"])
+	})
+
+	t.Run("约束错误定位到输入字段", func(t *testing.T) {
+		r := execute(`mutation { createUser(input: {id: 1, name: "a", email: "dup", createdAt: "2024-01-01T00:00:00Z"}) { id } }`)
+		assert.Equal(t, map[string]interface{}{"createUser": nil}, r.Data)
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, CODE_UNIQUE_VIOLATION, codeOf(r.Errors[0]))
+		assert.Equal(t, "email", r.Errors[0].Extensions["field"])
+		assert.Equal(t, []gqlerror.Location{{Line: 1, Column: 57}}, r.Errors[0].Locations)
+	})
+
+	t.Run("变更失败时不逐个重试根字段", func(t *testing.T) {
+		calls = 0
+		r := execute(`mutation {
+  a: createUser(input: {id: 1, name: "a", email: "dup", createdAt: "2024-01-01T00:00:00Z"}) { id }
+  b: createUser(input: {id: 2, name: "b", email: "new", createdAt: "2024-01-01T00:00:00Z"}) { id }
+}`)
+		assert.Equal(t, 1, calls, "已执行的根字段不会重复执行")
+		assert.Equal(t, map[string]interface{}{"a": nil, "b": nil}, r.Data)
+		require.Len(t, r.Errors, 2)
+		assert.Equal(t, ast.Path{ast.PathName("a")}, r.Errors[0].Path)
+		assert.Equal(t, ast.Path{ast.PathName("b")}, r.Errors[1].Path)
+	})
+
+	t.Run("编译错误定位到参数", func(t *testing.T) {
+		r := execute(`{ users(offset: 1) { total } }`)
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, CODE_COMPILE_FAILED, codeOf(r.Errors[0]))
+		assert.Equal(t, ast.Path{ast.PathName("users")}, r.Errors[0].Path)
+		assert.Equal(t, []gqlerror.Location{{Line: 1, Column: 9}}, r.Errors[0].Locations)
+	})
+
+	t.Run("解析和校验错误", func(t *testing.T) {
+		r := execute(`{ users { unknown } }`)
+		require.NotEmpty(t, r.Errors)
+		assert.Equal(t, CODE_GRAPHQL_VALIDATION_FAILED, codeOf(r.Errors[0]))
+		assert.Equal(t, []gqlerror.Location{{Line: 1, Column: 11}}, r.Errors[0].Locations)
+		assert.Nil(t, r.Data)
+
+		r = execute(`{ users {`)
+		require.NotEmpty(t, r.Errors)
+		assert.Equal(t, CODE_GRAPHQL_PARSE_FAILED, codeOf(r.Errors[0]))
+		assert.NotEmpty(t, r.Errors[0].Locations)
+	})
+}
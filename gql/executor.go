@@ -3,15 +3,27 @@
 package gql
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/duke-git/lancet/v2/strutil"
+	"github.com/fasthttp/websocket"
 	"github.com/gofiber/fiber/v3"
 	"github.com/ichaly/ideabase/gql/internal/intro"
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/ichaly/ideabase/std/event"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+	"go.opentelemetry.io/otel/codes"
 	"gorm.io/gorm"
 )
 
@@ -22,28 +34,81 @@ type (
 	gqlQuery struct {
 		Query         string                 `json:"query"`         // GraphQL查询文本
 		OperationName string                 `json:"operationName"` // 要执行的操作名称，多操作查询时必须
+		DocumentId    string                 `json:"documentId"`    // 可信文档ID，白名单模式下替代查询文本
 		Variables     map[string]interface{} `json:"variables"`     // 查询变量
+		Extensions    gqlExtensions          `json:"extensions"`    // 扩展字段，如自动持久化查询
 	}
 
 	// gqlReply 表示GraphQL响应
 	// 包含执行结果数据或错误信息
 	gqlReply struct {
-		sql    string                 // 生成的SQL语句，仅内部使用
-		args   []any                  // SQL参数，仅内部使用
-		Data   map[string]interface{} `json:"data,omitempty"`   // 成功结果数据
-		Errors gqlerror.List          `json:"errors,omitempty"` // 错误信息列表
+		Data       map[string]interface{} `json:"data,omitempty"`       // 成功结果数据
+		Errors     gqlerror.List          `json:"errors,omitempty"`     // 错误信息列表
+		Patch      []patchOperation       `json:"patch,omitempty"`      // 实时查询相对上次结果的JSON Patch
+		Extensions map[string]interface{} `json:"extensions,omitempty"` // 扩展信息，如执行追踪和执行计划
 	}
 )
 
 // Executor GraphQL执行器
 // 负责解析GraphQL查询、编译为SQL并执行查询，支持多种数据库方言
 // 可作为Fiber插件集成到Web服务中，提供标准的GraphQL API
+// 每个Executor实例都是一份不可变的schema快照，current指向当前生效的快照，热加载时整体原子替换
 type Executor struct {
-	intro    *intro.Handler // 自省处理器，处理__schema和__type查询
-	schema   *ast.Schema    // GraphQL模式定义
-	database *gorm.DB       // 数据库连接，用于执行生成的SQL
-	metadata *Metadata      // 元数据信息，包含表结构、关系等
-	compiler *Compiler      // 编译器，将GraphQL查询编译为SQL
+	intro       *intro.Handler            // 自省处理器，处理__schema和__type查询
+	schema      *ast.Schema               // GraphQL模式定义
+	database    *gorm.DB                  // 数据库连接，用于执行生成的SQL
+	metadata    *Metadata                 // 元数据信息，包含表结构、关系等
+	compiler    *Compiler                 // 编译器，将GraphQL查询编译为SQL
+	fingerprint string                    // 元数据指纹，用于跳过无变化的重建
+	current     *atomic.Pointer[Executor] // 当前生效的快照，所有快照共享
+	mu          *sync.Mutex               // 串行化重建过程
+	broker      *broker                   // 订阅中心，所有快照共享
+	auth        Authenticator             // WebSocket连接鉴权
+	cache       cache.Cache               // 持久化查询存储，为空时不支持APQ
+	persisted   *persistedQueries         // 最近注册的持久化查询，所有快照共享
+	trusted     *trustedDocuments         // 可信文档清单，所有快照共享
+	plans       *planCache                // 查询计划缓存，随schema快照替换
+	roles       map[string]*roleSchema    // 角色专属schema，未配置权限规则时为空
+	metrics     *std.Metrics              // 监控指标，为空时不记录操作数和耗时
+	labels      *operationLabels          // 操作指标的名称标签，所有快照共享
+}
+
+// ExecutorOption 执行器可选配置
+type ExecutorOption func(*executorOptions)
+
+type executorOptions struct {
+	bus     *event.Bus
+	auth    Authenticator
+	cache   cache.Cache
+	metrics *std.Metrics
+}
+
+// WithEventBus 设置事件总线，订阅依赖表变更事件重新推送结果
+func WithEventBus(bus *event.Bus) ExecutorOption {
+	return func(o *executorOptions) {
+		o.bus = bus
+	}
+}
+
+// WithAuthenticator 设置WebSocket连接鉴权函数
+func WithAuthenticator(auth Authenticator) ExecutorOption {
+	return func(o *executorOptions) {
+		o.auth = auth
+	}
+}
+
+// WithCache 设置缓存，用于存储自动持久化查询
+func WithCache(c cache.Cache) ExecutorOption {
+	return func(o *executorOptions) {
+		o.cache = c
+	}
+}
+
+// WithMetrics 设置监控指标，按操作类型和名称记录操作数和耗时
+func WithMetrics(m *std.Metrics) ExecutorOption {
+	return func(o *executorOptions) {
+		o.metrics = m
+	}
 }
 
 // 构造函数和初始化方法
@@ -53,6 +118,8 @@ type Executor struct {
 //   - d: 数据库连接(gorm.DB)
 //   - r: GraphQL模式渲染器
 //   - m: 数据库元数据
+//   - c: SQL编译器
+//   - opts: 可选配置，如事件总线、连接鉴权、持久化查询缓存、监控指标
 //
 // 返回:
 //   - 执行器实例和可能的错误
@@ -60,35 +127,74 @@ type Executor struct {
 // 使用示例:
 //
 //	renderer := gql.NewRenderer(metadata)
-//	executor, err := gql.NewExecutor(db, renderer, metadata)
+//	executor, err := gql.NewExecutor(db, renderer, metadata, compiler,
+//	    gql.WithEventBus(bus),
+//	    gql.WithCache(store),
+//	    gql.WithMetrics(metrics),
+//	    gql.WithAuthenticator(func(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+//	        return ctx, nil
+//	    }),
+//	)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func NewExecutor(d *gorm.DB, r *Renderer, m *Metadata, c *Compiler) (*Executor, error) {
+func NewExecutor(d *gorm.DB, r *Renderer, m *Metadata, c *Compiler, opts ...ExecutorOption) (*Executor, error) {
+	options := &executorOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
 	executor := &Executor{
-		database: d,
-		metadata: m,
-		compiler: c,
+		database:    d,
+		metadata:    m,
+		compiler:    c,
+		fingerprint: m.fingerprint(),
+		current:     &atomic.Pointer[Executor]{},
+		mu:          &sync.Mutex{},
+		broker:      newBroker(options.bus),
+		auth:        options.auth,
+		cache:       options.cache,
+		persisted:   newPersistedQueries(persistedRecent),
+		trusted:     &trustedDocuments{},
+		plans:       newPlanCache(m.cfg.Executor.Plan.Size),
+		metrics:     options.metrics,
+		labels:      &operationLabels{seen: make(map[string]bool)},
 	}
 
-	// 生成并加载GraphQL模式
-	data, err := r.Generate()
-	if err != nil {
-		return nil, err
+	// 加载可信文档清单，白名单模式下清单不可用时拒绝启动
+	if err := executor.trusted.load(m.cfg); err != nil {
+		if m.cfg.Executor.Trusted.Enable {
+			return nil, err
+		}
+		log.Warn().Err(err).Msg("可信文档清单不可用")
 	}
-	s, err := gqlparser.LoadSchema(&ast.Source{
-		Name:  "schema.graphql",
-		Input: data,
-	})
+
+	// 生成并加载GraphQL模式
+	s, err := loadSchema(r)
 	if err != nil {
 		return nil, err
 	}
 
 	executor.schema = s
 	executor.intro = intro.New(s)
+	if executor.roles, err = loadRoleSchemas(m); err != nil {
+		return nil, err
+	}
+	executor.current.Store(executor)
 	return executor, nil
 }
 
+// loadSchema 渲染并解析GraphQL模式
+func loadSchema(r *Renderer) (*ast.Schema, error) {
+	data, err := r.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return gqlparser.LoadSchema(&ast.Source{
+		Name:  "schema.graphql",
+		Input: data,
+	})
+}
+
 // 接口实现方法
 
 // Path 实现Plugin接口的Path方法，返回插件的基础路径
@@ -105,10 +211,12 @@ func (my *Executor) Path() string {
 func (my *Executor) Bind(r fiber.Router) {
 	// 注册GraphQL请求处理路由
 	r.Post("/", my.Handler)
+	// 注册GET查询和graphql-transport-ws订阅路由
+	r.Get("/", my.Query)
 }
 
 // Handler 处理GraphQL HTTP请求
-// 作为Fiber中间件函数，解析请求体中的GraphQL查询并执行
+// 作为Fiber中间件函数，解析请求体中的GraphQL查询并执行，请求体为数组时批量执行
 // 参数:
 //   - c: Fiber上下文，包含HTTP请求和响应信息
 //
@@ -119,6 +227,11 @@ func (my *Executor) Bind(r fiber.Router) {
 //
 //	app.Post("/graphql", executor.Handler)
 func (my *Executor) Handler(c fiber.Ctx) error {
+	// 请求体为JSON数组时按批量请求处理
+	if body := bytes.TrimSpace(c.Body()); len(body) > 0 && body[0] == '[' {
+		return my.batch(c, body)
+	}
+
 	// 解析请求
 	var req gqlQuery
 	if err := c.Bind().Body(&req); err != nil {
@@ -126,14 +239,80 @@ func (my *Executor) Handler(c fiber.Ctx) error {
 			"errors": []gqlerror.Error{*gqlerror.Wrap(err)},
 		})
 	}
+	return my.serve(c, req)
+}
+
+// Query 处理GraphQL GET请求
+// WebSocket升级请求交给WebSocket处理，其余从查询参数中解析query、documentId、variables、operationName和extensions，
+// variables和extensions为JSON字符串；GET请求只允许执行查询，便于CDN和浏览器缓存
+//
+// 使用示例:
+//
+//	GET /graphql?query={users{total}}&variables={}
+func (my *Executor) Query(c fiber.Ctx) error {
+	if websocket.FastHTTPIsWebSocketUpgrade(c.RequestCtx()) {
+		return my.WebSocket(c)
+	}
+
+	req := gqlQuery{Query: c.Query("query"), OperationName: c.Query("operationName"), DocumentId: c.Query("documentId")}
+	for name, v := range map[string]interface{}{"variables": &req.Variables, "extensions": &req.Extensions} {
+		if raw := c.Query(name); raw != "" {
+			if err := json.Unmarshal([]byte(raw), v); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"errors": []gqlerror.Error{*gqlerror.Wrap(fmt.Errorf("无效的%s参数: %w", name, err))},
+				})
+			}
+		}
+	}
+	return my.serve(c, req)
+}
+
+// serve 解析可信文档或持久化查询后执行请求，并按请求方法和执行结果设置Cache-Control
+func (my *Executor) serve(c fiber.Ctx, req gqlQuery) error {
+	get := c.Method() == fiber.MethodGet
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	query, gErr := my.resolveQuery(c.Context(), req)
+	if gErr != nil {
+		return c.JSON(gqlReply{Errors: gqlerror.List{gErr}})
+	}
+	req.Query = query
+
+	// GET请求可能被缓存或预取，禁止执行变更
+	if get && isMutation(req.Query, req.OperationName) {
+		c.Set(fiber.HeaderAllow, fiber.MethodPost)
+		return c.Status(fiber.StatusMethodNotAllowed).JSON(gqlReply{
+			Errors: gqlerror.List{gqlerror.Errorf("GET请求不支持变更操作，请使用POST")},
+		})
+	}
+
+	// 客户端接受事件流时以SSE推送订阅和实时查询结果
+	if strings.Contains(c.Get(fiber.HeaderAccept), sseContentType) {
+		return my.stream(c, req)
+	}
 
 	// 直接使用map类型的变量
 	result := my.Execute(c.Context(), req.Query, req.Variables, req.OperationName)
 
+	// 只有成功的GET查询允许缓存
+	if get && len(result.Errors) == 0 {
+		c.Set(fiber.HeaderCacheControl, my.current.Load().metadata.cfg.Executor.CacheControl.String())
+	}
+
 	// 返回结果
 	return c.JSON(result)
 }
 
+// isMutation 判断请求选中的操作是否为变更，语法错误留给执行阶段统一报告
+func isMutation(query, operationName string) bool {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return false
+	}
+	operation, opErr := getOperation(doc.Operations, operationName)
+	return opErr == nil && operation.Operation == ast.Mutation
+}
+
 // 主要公开方法
 
 // Execute 执行GraphQL查询并返回结果
@@ -153,37 +332,195 @@ func (my *Executor) Handler(c fiber.Ctx) error {
 //	    "query { user(id: 1) { name email } }",
 //	    nil, "")
 func (my *Executor) Execute(ctx context.Context, query string, variables map[string]interface{}, operationName string) gqlReply {
-	var r gqlReply
+	// 请求开始时固定快照，热加载不会影响进行中的请求
+	return my.current.Load().execute(ctx, query, variables, operationName)
+}
 
-	// 处理自省查询
-	if strutil.ContainsAny(query, []string{"__schema", "__type"}) {
-		data, err := my.intro.Introspect(ctx, query, variables)
-		if err != nil {
-			r.Errors = gqlerror.List{gqlerror.Wrap(err)}
-			return r
+// execute 在当前快照上执行GraphQL查询，开启执行追踪时在extensions.tracing中返回各阶段耗时
+// 每个操作创建一个链路追踪span，数据库语句的span挂在其下
+func (my *Executor) execute(ctx context.Context, query string, variables map[string]interface{}, operationName string) gqlReply {
+	ctx, span := tracer.Start(ctx, SPAN_OPERATION)
+	defer span.End()
+	ctx, t := my.withTracing(ctx)
+	r := my.run(ctx, query, variables, operationName)
+	if len(r.Errors) > 0 {
+		span.SetStatus(codes.Error, r.Errors[0].Message)
+	}
+	if t != nil {
+		if r.Extensions == nil {
+			r.Extensions = make(map[string]interface{}, 1)
 		}
+		r.Extensions["tracing"] = t.extension()
+	}
+	return r
+}
 
-		r.Data = data
+// run 校验并执行GraphQL查询
+func (my *Executor) run(ctx context.Context, query string, variables map[string]interface{}, operationName string) (r gqlReply) {
+	start := time.Now()
+
+	// 白名单模式下只执行清单中的文档
+	if my.metadata.cfg.Executor.Trusted.Enable && !my.trusted.contains(query) {
+		r.Errors = gqlerror.List{newCodeError(CODE_TRUSTED_DOCUMENT_REQUIRED, "白名单模式下只能执行可信文档")}
 		return r
 	}
 
-	// 解析查询
-	doc, err := gqlparser.LoadQuery(my.schema, query)
+	// 按请求角色选择schema，权限规则引用的当前用户通过保留变量传给编译器
+	role, variables := my.authorize(ctx, variables)
+	if _, _, ok := my.schemaFor(role); !ok {
+		r.Errors = gqlerror.List{newCodeError(CODE_FORBIDDEN, fmt.Sprintf("角色%s无权访问", role))}
+		return r
+	}
+
+	// 解析查询，相同的文档、操作名和角色复用缓存的AST
+	entry, err := my.prepare(ctx, query, operationName, role)
 	if err != nil {
-		r.Errors = gqlerror.List{gqlerror.Wrap(err)}
+		r.Errors = documentErrors(err)
 		return r
 	}
+	operation := entry.operation
+	traceOperation(ctx, operation)
+	// 解析出操作后才能确定类型和名称，未解析成功的请求不计入操作指标
+	defer func() {
+		my.metrics.ObserveOperation(string(operation.Operation), my.operationLabel(query, operation.Name), time.Since(start), len(r.Errors) > 0)
+	}()
+
+	// 编译SQL之前拒绝超出深度、别名或成本预算的查询
+	if errs := my.checkComplexity(operation, variables); len(errs) > 0 {
+		r.Errors = errs
+		return r
+	}
+
+	// @explain只返回执行计划，不执行操作
+	if explainOperation(operation) {
+		return my.explain(ctx, entry, variables)
+	}
+
+	if entry.data != nil {
+		// 超时从数据字段开始执行时计时，取消后数据库查询随之中止
+		runCtx, cancel := my.withTimeout(ctx)
+		r = my.runData(runCtx, entry, variables)
+		cancel()
+	}
+
+	// 自省字段直接由schema解析，与数据字段的结果合并
+	if len(entry.intro) > 0 {
+		data, errs := my.introspect(entry.intro, variables, role)
+		if r.Data == nil {
+			r.Data = make(map[string]interface{}, len(data))
+		}
+		for k, v := range data {
+			r.Data[k] = v
+		}
+		r.Errors = append(r.Errors, errs...)
+	}
+	return r
+}
+
+// runData 执行操作中的数据字段，开启Relay时先解析node/nodes根字段
+func (my *Executor) runData(ctx context.Context, entry *planEntry, variables map[string]interface{}) gqlReply {
+	operation := entry.data
+	// 配置只读副本时查询路由到副本，变更使用主库
+	if operation.Operation == ast.Mutation {
+		ctx = std.UsePrimary(ctx)
+	} else {
+		ctx = std.UseReplica(ctx)
+	}
+	if my.metadata.cfg.Schema.Relay && operation.Operation == ast.Query {
+		r := my.runRelayOperation(ctx, operation, variables)
+		fillTypename(entry.operation.SelectionSet, r.Data)
+		return r
+	}
+
+	r := my.runOperation(ctx, entry, operation, my.decodeNodeArgs(operation, variables))
+	my.encodeNodeIds(operation.SelectionSet, r.Data)
+	fillTypename(entry.operation.SelectionSet, r.Data)
+	// 变更成功后广播表变更，使订阅和实时查询重新执行
+	if operation.Operation == ast.Mutation && len(r.Errors) == 0 {
+		my.broker.publish(ctx, my.mutationChanges(operation))
+	}
+	return r
+}
+
+// runRelayOperation 执行包含Relay节点查询的操作
+// node/nodes根字段单独路由到对应的表，其余字段仍交给编译器处理
+func (my *Executor) runRelayOperation(ctx context.Context, operation *ast.OperationDefinition, variables map[string]interface{}) gqlReply {
+	data, rest, errs := my.resolveNodes(ctx, operation, variables)
+	if len(rest) == 0 {
+		return gqlReply{Data: data, Errors: errs}
+	}
+
+	op := *operation
+	op.SelectionSet = rest
+	r := my.runOperation(ctx, nil, &op, my.decodeNodeArgs(&op, variables))
+	my.encodeNodeIds(rest, r.Data)
+	for k, v := range data {
+		if r.Data == nil {
+			r.Data = make(map[string]interface{})
+		}
+		r.Data[k] = v
+	}
+	r.Errors = append(r.Errors, errs...)
+	return r
+}
+
+// prepare 按角色的schema解析并校验操作，按规范化文档摘要、操作名和角色缓存
+func (my *Executor) prepare(ctx context.Context, query, operationName, role string) (*planEntry, error) {
+	key := planKey(query, operationName, role)
+	if entry, ok := my.plans.get(key); ok {
+		return entry, nil
+	}
 
+	schema, _, ok := my.schemaFor(role)
+	if !ok {
+		return nil, fmt.Errorf("角色%s无权访问", role)
+	}
+	t := traceFrom(ctx)
+	start := time.Now()
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	t.since(phaseParse, start)
+	if gErr, ok := err.(*gqlerror.Error); ok {
+		return nil, gqlerror.List{gErr}
+	} else if err != nil {
+		return nil, err
+	}
+	start = time.Now()
+	errs := validator.Validate(schema, doc)
+	t.since(phaseValidate, start)
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	// 按照GraphQL规范处理操作
 	operation, opErr := getOperation(doc.Operations, operationName)
 	if opErr != nil {
-		r.Errors = gqlerror.List{gqlerror.Wrap(opErr)}
-		return r
+		return nil, opErr
 	}
+	// 缓存前替换字面量中的全局ID，缓存的AST在并发请求之间只读
+	my.decodeNodeArgs(operation, nil)
 
-	// 执行选定的操作
-	r = my.runOperation(operation, variables)
-	return r
+	entry := &planEntry{key: key, operation: operation}
+	entry.intro, entry.data = splitIntrospection(operation)
+	my.plans.add(entry)
+	return entry, nil
+}
+
+// build 编译操作，缓存的操作复用首次编译的SQL模板并按变量重新绑定参数
+func (my *Executor) build(entry *planEntry, operation *ast.OperationDefinition, variables map[string]interface{}) (string, []any, error) {
+	if entry == nil {
+		return my.compiler.Build(operation, variables)
+	}
+	if p := entry.plan.Load(); p != nil {
+		args, err := p.bind(variables)
+		return p.sql, args, err
+	}
+	p, err := my.compiler.compile(operation, variables)
+	if err != nil {
+		return "", nil, err
+	}
+	if p.cacheable {
+		entry.plan.Store(p)
+	}
+	return p.sql, p.args, nil
 }
 
 // 获取操作
@@ -216,36 +553,87 @@ func getOperation(operations ast.OperationList, operationName string) (*ast.Oper
 	return nil, fmt.Errorf("未找到名为'%s'的操作", operationName)
 }
 
+// decodeRoot 将根结果列解析为对象
+func decodeRoot(root interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	switch v := root.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case []byte:
+		return result, json.Unmarshal(v, &result)
+	case string:
+		return result, json.Unmarshal([]byte(v), &result)
+	case nil:
+		return result, nil
+	}
+	return nil, fmt.Errorf("无法解析的根结果类型: %T", root)
+}
+
 // runOperation 执行单个GraphQL操作
-// 将操作编译为SQL并执行，然后处理结果
+// 所有根字段编译为一条SQL执行，查询失败时逐个执行根字段，相互独立的根字段互不影响
+// 失败的根字段置为null，错误中记录路径和位置；请求取消或超时后不再逐个重试
+// 变更的整条语句已经回滚，逐个重试会重复执行其余根字段，失败时所有根字段都返回同一错误
 // 参数:
-//   - op: 要执行的GraphQL操作定义
+//   - ctx: 请求上下文，取消或超时后中止数据库查询
+//   - entry: 缓存的操作，为空时不缓存编译结果
+//   - operation: 要执行的GraphQL操作定义
 //   - variables: 操作变量
 //
 // 返回:
 //   - 包含执行结果或错误的GraphQL响应
-func (my *Executor) runOperation(operation *ast.OperationDefinition, variables map[string]interface{}) gqlReply {
+func (my *Executor) runOperation(ctx context.Context, entry *planEntry, operation *ast.OperationDefinition, variables map[string]interface{}) gqlReply {
 	var r gqlReply
-
-	// 编译并执行SQL查询
 	var err error
-	if r.sql, r.args, err = my.compiler.Build(operation, variables); err != nil {
-		r.Errors = append(r.Errors, gqlerror.Wrap(err))
+	if r.Data, err = my.query(ctx, entry, operation, variables); err == nil {
 		return r
 	}
 
-	result := make(map[string]interface{})
-	if err = my.database.Raw(r.sql, r.args...).Scan(&result).Error; err != nil {
-		r.Errors = append(r.Errors, gqlerror.Wrap(err))
+	fields := rootFields(operation.SelectionSet)
+	r.Data = make(map[string]interface{}, len(fields))
+	if len(fields) == 1 || operation.Operation != ast.Query || ctx.Err() != nil {
+		for _, field := range fields {
+			r.Data[field.Alias] = nil
+			r.Errors = append(r.Errors, my.fieldError(field, err))
+		}
 		return r
 	}
+	for _, field := range fields {
+		op := *operation
+		op.SelectionSet = ast.SelectionSet{field}
+		data, fieldErr := my.query(ctx, nil, &op, variables)
+		if fieldErr != nil {
+			r.Data[field.Alias] = nil
+			r.Errors = append(r.Errors, my.fieldError(field, fieldErr))
+			continue
+		}
+		r.Data[field.Alias] = data[field.Alias]
+	}
+	return r
+}
 
-	// 按GraphQL规范组织结果
-	if operation.Name != "" {
-		r.Data = map[string]interface{}{operation.Name: result}
-	} else {
-		r.Data = result
+// query 编译并执行操作，返回以根字段别名组织的结果
+func (my *Executor) query(ctx context.Context, entry *planEntry, operation *ast.OperationDefinition, variables map[string]interface{}) (map[string]interface{}, error) {
+	t := traceFrom(ctx)
+	start := time.Now()
+	sql, args, err := my.build(entry, operation, variables)
+	t.since(phaseCompile, start)
+	if err != nil {
+		return nil, err
 	}
+	t.record(sql, args)
 
-	return r
+	start = time.Now()
+	result := make(map[string]interface{})
+	err = my.scan(ctx, sql, args, &result)
+	t.since(phaseExecute, start)
+	if err != nil {
+		return nil, err
+	}
+	// 方言将根结果聚合为__root列的JSON，这里展开为普通对象
+	if root, ok := result[ROOT]; ok {
+		if result, err = decodeRoot(root); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
@@ -1,12 +1,138 @@
 package internal
 
-import "github.com/ichaly/ideabase/std"
+import (
+	"fmt"
+	"time"
+
+	"github.com/ichaly/ideabase/std"
+)
 
 // Config 表示GraphQL配置
 type Config struct {
 	std.Config `mapstructure:",squash"`
 	Schema     SchemaConfig   `mapstructure:"schema"`
 	Metadata   MetadataConfig `mapstructure:"metadata"`
+	Executor   ExecutorConfig `mapstructure:"executor"`
+}
+
+// ExecutorConfig 表示执行器配置
+type ExecutorConfig struct {
+	// 单个操作的执行超时，同时设置为数据库的语句超时，0表示不限制
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// WebSocket订阅配置
+	Subscription SubscriptionConfig `mapstructure:"subscription"`
+
+	// 自动持久化查询配置
+	Persisted PersistedConfig `mapstructure:"persisted"`
+
+	// GET查询的HTTP缓存配置
+	CacheControl CacheControlConfig `mapstructure:"cache-control"`
+
+	// 可信文档白名单配置
+	Trusted TrustedConfig `mapstructure:"trusted"`
+
+	// 批量请求配置
+	Batch BatchConfig `mapstructure:"batch"`
+
+	// 查询复杂度限制
+	Limit LimitConfig `mapstructure:"limit"`
+
+	// 查询计划缓存配置
+	Plan PlanConfig `mapstructure:"plan"`
+
+	// 执行追踪配置
+	Tracing TracingConfig `mapstructure:"tracing"`
+
+	// 操作指标配置
+	Metrics MetricsConfig `mapstructure:"metrics"`
+}
+
+// MetricsConfig 表示操作指标的名称标签配置
+type MetricsConfig struct {
+	// 始终作为标签的操作名，可信文档清单中的操作名同样始终作为标签
+	Operations []string `mapstructure:"operations"`
+
+	// 其他操作名按出现顺序作为标签的数量上限，超过后统计为other，0表示只使用上述操作名
+	MaxOperations int `mapstructure:"max-operations"`
+}
+
+// TracingConfig 表示执行追踪配置
+type TracingConfig struct {
+	// 是否在响应的extensions.tracing中返回各阶段耗时并支持@explain指令，开发模式下同时返回执行的SQL和参数
+	Enable bool `mapstructure:"enable"`
+}
+
+// PlanConfig 表示解析后的文档与编译SQL的缓存配置
+type PlanConfig struct {
+	// 缓存的最大操作数，按最近最少使用淘汰，0表示不缓存
+	Size int `mapstructure:"size"`
+}
+
+// LimitConfig 表示查询复杂度限制，各项为0表示不限制
+type LimitConfig struct {
+	// 最大查询深度
+	MaxDepth int `mapstructure:"max-depth"`
+
+	// 最大别名数量
+	MaxAliases int `mapstructure:"max-aliases"`
+
+	// 最大估算成本，成本为各类权重乘以关联展开的行数
+	MaxCost int `mapstructure:"max-cost"`
+}
+
+// BatchConfig 表示JSON数组批量请求配置
+type BatchConfig struct {
+	// 单次批量请求允许的最大操作数，0表示不支持批量请求
+	MaxSize int `mapstructure:"max-size"`
+
+	// 批量查询的最大并发数，变更始终按顺序执行
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// TrustedConfig 表示可信文档白名单配置
+type TrustedConfig struct {
+	// 是否只接受清单中的文档，生产环境的公开应用建议开启
+	Enable bool `mapstructure:"enable"`
+
+	// 清单文件路径，JSON格式，键为文档ID，值为GraphQL文档，文件变更后自动重新加载
+	Manifest string `mapstructure:"manifest"`
+}
+
+// PersistedConfig 表示自动持久化查询（APQ）配置
+type PersistedConfig struct {
+	// 查询文档在缓存中的保留时间
+	Ttl time.Duration `mapstructure:"ttl"`
+}
+
+// CacheControlConfig 表示GET查询响应的Cache-Control配置
+type CacheControlConfig struct {
+	// 成功查询的缓存时间，0表示要求客户端每次校验
+	MaxAge time.Duration `mapstructure:"max-age"`
+
+	// 是否只允许客户端缓存，响应与用户相关时应开启，避免被CDN共享
+	Private bool `mapstructure:"private"`
+}
+
+// String 生成成功查询响应的Cache-Control头
+func (my CacheControlConfig) String() string {
+	if my.MaxAge <= 0 {
+		return "no-cache"
+	}
+	scope := "public"
+	if my.Private {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(my.MaxAge.Seconds()))
+}
+
+// SubscriptionConfig 表示graphql-transport-ws订阅配置
+type SubscriptionConfig struct {
+	// 等待connection_init的超时时间
+	InitTimeout time.Duration `mapstructure:"init-timeout"`
+
+	// 服务端主动发送ping的间隔，0表示不发送
+	KeepAlive time.Duration `mapstructure:"keep-alive"`
 }
 
 // SchemaConfig 表示Schema相关配置
@@ -17,8 +143,14 @@ type SchemaConfig struct {
 	// 默认分页限制
 	DefaultLimit int `mapstructure:"default-limit"`
 
+	// 最大分页限制，请求的limit/first/last超过时拒绝执行，0表示不限制
+	MaxLimit int `mapstructure:"max-limit"`
+
 	// 数据类型映射
 	TypeMapping map[string]string `mapstructure:"mapping"`
+
+	// 是否启用Relay规范(Node接口与全局ID)
+	Relay bool `mapstructure:"relay"`
 }
 
 // MetadataConfig 表示元数据配置
@@ -28,6 +160,7 @@ type MetadataConfig struct {
 
 	// 文件配置
 	File string `mapstructure:"file"` // 支持 {mode} 占位符
+	Sdl  string `mapstructure:"sdl"`  // schema-first的SDL文件，支持 {mode} 占位符
 
 	// 命名规范
 	UseCamel    bool `mapstructure:"use-camel"`
@@ -44,6 +177,36 @@ type MetadataConfig struct {
 
 	// 要排除的字段
 	ExcludeFields []string `mapstructure:"exclude-fields"`
+
+	// 热加载配置
+	Reload ReloadConfig `mapstructure:"reload"`
+
+	// 严格模式：校验最终元数据，存在问题时启动失败(生产模式默认开启)
+	Strict bool `mapstructure:"strict"`
+
+	// 请求角色的识别方式，配合类的权限规则使用
+	Roles RoleConfig `mapstructure:"roles"`
+}
+
+// RoleConfig 表示请求角色的识别方式
+type RoleConfig struct {
+	// JWT负载中表示角色的声明名称
+	Claim string `mapstructure:"claim"`
+
+	// 已登录但未声明角色时使用的角色
+	Default string `mapstructure:"default"`
+
+	// 未登录请求使用的角色
+	Anonymous string `mapstructure:"anonymous"`
+}
+
+// ReloadConfig 表示元数据热加载配置
+type ReloadConfig struct {
+	// 是否监听配置文件和元数据文件变更
+	Watch bool `mapstructure:"watch"`
+
+	// 定时重建间隔，用于感知数据库结构变化，0表示不启用
+	Interval time.Duration `mapstructure:"interval"`
 }
 
 // ClassConfig 表示类配置
@@ -72,6 +235,25 @@ type ClassConfig struct {
 
 	// override: true 表示别名覆盖主类指针，false（默认）为附加模式
 	Override bool `mapstructure:"override"`
+
+	// 查询成本权重，未设置时为1
+	Cost int `mapstructure:"cost"`
+
+	// 按角色配置的权限规则(key: 角色名)，未配置时不限制访问
+	Permissions map[string]*PermissionConfig `mapstructure:"permissions"`
+}
+
+// PermissionConfig 表示角色在类上的权限规则
+type PermissionConfig struct {
+	// 允许的操作: query、create、update、delete
+	Operations []string `mapstructure:"operations"`
+
+	// 行过滤条件，格式与where参数一致，值可以引用$user和$claims.<声明名>
+	Filter map[string]interface{} `mapstructure:"filter"`
+
+	// 字段过滤配置
+	ExcludeFields []string `mapstructure:"exclude_fields"` // 禁止访问这些字段
+	IncludeFields []string `mapstructure:"include_fields"` // 仅允许访问这些字段
 }
 
 // FieldConfig 表示字段配置
@@ -1,8 +1,8 @@
 package intro
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/vektah/gqlparser/v2/ast"
@@ -18,42 +18,85 @@ func New(schema *ast.Schema) *Handler {
 	return &Handler{schema: schema}
 }
 
-// Introspect 处理自省查询
-func (my *Handler) Introspect(ctx context.Context, query string, variables map[string]interface{}) (map[string]interface{}, error) {
-	if strings.Contains(query, "__schema") {
-		return my.handleSchemaQuery(variables)
-	} else if strings.Contains(query, "__type") {
-		typeName, ok := variables["name"].(string)
+// Resolve 解析根选择集中的自省字段，结果按字段的选择集裁剪
+// __type的name参数支持字面量和变量，__typename返回根操作类型名
+func (my *Handler) Resolve(field *ast.Field, variables map[string]interface{}) (interface{}, error) {
+	switch field.Name {
+	case "__typename":
+		return field.ObjectDefinition.Name, nil
+	case "__schema":
+		return my.project(field.SelectionSet, my.getSchemaInfo(), variables), nil
+	case "__type":
+		typeName, ok := field.ArgumentMap(variables)["name"].(string)
 		if !ok {
 			return nil, errors.New("__type查询需要提供name参数")
 		}
-		return my.handleTypeQuery(typeName)
+		typeDef := my.schema.Types[typeName]
+		if typeDef == nil {
+			return nil, nil
+		}
+		return my.project(field.SelectionSet, my.getFullType(typeDef), variables), nil
 	}
-
-	return nil, errors.New("不是有效的自省查询")
+	return nil, fmt.Errorf("不是有效的自省字段: %s", field.Name)
 }
 
-// handleSchemaQuery 处理__schema查询
-func (my *Handler) handleSchemaQuery(variables map[string]interface{}) (map[string]interface{}, error) {
-	result := map[string]interface{}{
-		"__schema": my.getSchemaInfo(),
+// project 按选择集裁剪自省结果，支持别名、片段和__typename
+func (my *Handler) project(set ast.SelectionSet, value interface{}, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(set))
+		my.collect(set, v, result, variables)
+		return result
+	case []map[string]interface{}:
+		list := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, my.project(set, item, variables).(map[string]interface{}))
+		}
+		return list
 	}
-
-	return result, nil
+	return value
 }
 
-// handleTypeQuery 处理__type查询
-func (my *Handler) handleTypeQuery(typeName string) (map[string]interface{}, error) {
-	typeDef := my.schema.Types[typeName]
-	if typeDef == nil {
-		return map[string]interface{}{
-			"__type": nil,
-		}, nil
+// collect 将选择集中的字段从源对象复制到结果对象，片段中的字段合并到同一对象
+func (my *Handler) collect(set ast.SelectionSet, src, dst map[string]interface{}, variables map[string]interface{}) {
+	for _, s := range set {
+		switch f := s.(type) {
+		case *ast.Field:
+			if f.Name == "__typename" {
+				dst[f.Alias] = f.ObjectDefinition.Name
+				continue
+			}
+			child := src[f.Name]
+			if child != nil && len(f.SelectionSet) > 0 {
+				child = my.project(f.SelectionSet, filterDeprecated(f, child, variables), variables)
+			}
+			dst[f.Alias] = child
+		case *ast.InlineFragment:
+			my.collect(f.SelectionSet, src, dst, variables)
+		case *ast.FragmentSpread:
+			if f.Definition != nil {
+				my.collect(f.Definition.SelectionSet, src, dst, variables)
+			}
+		}
 	}
+}
 
-	return map[string]interface{}{
-		"__type": my.getFullType(typeDef),
-	}, nil
+// filterDeprecated 字段定义了includeDeprecated参数且未开启时，过滤已弃用的字段和枚举值
+func filterDeprecated(field *ast.Field, value interface{}, variables map[string]interface{}) interface{} {
+	list, ok := value.([]map[string]interface{})
+	if !ok || field.Definition == nil || field.Definition.Arguments.ForName("includeDeprecated") == nil {
+		return value
+	}
+	if include, _ := field.ArgumentMap(variables)["includeDeprecated"].(bool); include {
+		return value
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if deprecated, _ := item["isDeprecated"].(bool); !deprecated {
+			result = append(result, item)
+		}
+	}
+	return result
 }
 
 // getSchemaInfo 获取Schema信息
@@ -62,15 +105,15 @@ func (my *Handler) getSchemaInfo() map[string]interface{} {
 
 	// 添加查询、变更和订阅类型
 	if my.schema.Query != nil {
-		result["queryType"] = map[string]string{"name": my.schema.Query.Name}
+		result["queryType"] = my.getFullType(my.schema.Query)
 	}
 
 	if my.schema.Mutation != nil {
-		result["mutationType"] = map[string]string{"name": my.schema.Mutation.Name}
+		result["mutationType"] = my.getFullType(my.schema.Mutation)
 	}
 
 	if my.schema.Subscription != nil {
-		result["subscriptionType"] = map[string]string{"name": my.schema.Subscription.Name}
+		result["subscriptionType"] = my.getFullType(my.schema.Subscription)
 	}
 
 	// 添加所有类型
@@ -0,0 +1,134 @@
+package gql
+
+import (
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// splitIntrospection 按根字段拆分自省字段和数据字段
+// 根选择集中的片段展开为字段；数据字段中的__typename在编译前移除，执行后由fillTypename回填
+// 没有数据字段时返回的操作为空
+func splitIntrospection(operation *ast.OperationDefinition) ([]*ast.Field, *ast.OperationDefinition) {
+	var intro []*ast.Field
+	data := make(ast.SelectionSet, 0, len(operation.SelectionSet))
+	for _, field := range rootFields(operation.SelectionSet) {
+		switch {
+		case strings.HasPrefix(field.Name, "__"):
+			intro = append(intro, field)
+		case field.Name == NODE || field.Name == NODES:
+			// node/nodes按具体类型解析__typename
+			data = append(data, field)
+		default:
+			f := *field
+			f.SelectionSet = stripTypename(field.SelectionSet)
+			data = append(data, &f)
+		}
+	}
+	if len(data) == 0 {
+		return intro, nil
+	}
+	op := *operation
+	op.SelectionSet = data
+	return intro, &op
+}
+
+// rootFields 展开根选择集中的内联片段和片段引用
+func rootFields(set ast.SelectionSet) []*ast.Field {
+	var list []*ast.Field
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.Field:
+			list = append(list, v)
+		case *ast.InlineFragment:
+			list = append(list, rootFields(v.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if v.Definition != nil {
+				list = append(list, rootFields(v.Definition.SelectionSet)...)
+			}
+		}
+	}
+	return list
+}
+
+// stripTypename 复制选择集并移除其中的__typename字段，原选择集保持不变
+func stripTypename(set ast.SelectionSet) ast.SelectionSet {
+	if len(set) == 0 {
+		return set
+	}
+	list := make(ast.SelectionSet, 0, len(set))
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.Field:
+			if v.Name == TYPENAME {
+				continue
+			}
+			f := *v
+			f.SelectionSet = stripTypename(v.SelectionSet)
+			list = append(list, &f)
+		case *ast.InlineFragment:
+			i := *v
+			i.SelectionSet = stripTypename(v.SelectionSet)
+			list = append(list, &i)
+		case *ast.FragmentSpread:
+			if v.Definition == nil {
+				list = append(list, v)
+				continue
+			}
+			d := *v.Definition
+			d.SelectionSet = stripTypename(v.Definition.SelectionSet)
+			spread := *v
+			spread.Definition = &d
+			list = append(list, &spread)
+		}
+	}
+	return list
+}
+
+// fillTypename 按原始选择集在结果中回填__typename
+// node/nodes的结果已由fetchNodes按具体类型填充，这里跳过
+func fillTypename(set ast.SelectionSet, value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			fillTypename(set, item)
+		}
+	case map[string]interface{}:
+		for _, s := range set {
+			switch f := s.(type) {
+			case *ast.Field:
+				if f.Name == TYPENAME {
+					if def := f.ObjectDefinition; def != nil && def.Kind == ast.Object {
+						v[f.Alias] = def.Name
+					}
+				} else if len(f.SelectionSet) > 0 && f.Name != NODE && f.Name != NODES {
+					if child := v[f.Alias]; child != nil {
+						fillTypename(f.SelectionSet, child)
+					}
+				}
+			case *ast.InlineFragment:
+				fillTypename(f.SelectionSet, v)
+			case *ast.FragmentSpread:
+				if f.Definition != nil {
+					fillTypename(f.Definition.SelectionSet, v)
+				}
+			}
+		}
+	}
+}
+
+// introspect 按角色的schema解析根选择集中的自省字段
+func (my *Executor) introspect(fields []*ast.Field, variables map[string]interface{}, role string) (map[string]interface{}, gqlerror.List) {
+	data := make(map[string]interface{}, len(fields))
+	var errs gqlerror.List
+	_, handler, _ := my.schemaFor(role)
+	for _, field := range fields {
+		value, err := handler.Resolve(field, variables)
+		if err != nil {
+			errs = append(errs, gqlerror.ErrorPosf(field.Position, "%s", err.Error()))
+		}
+		data[field.Alias] = value
+	}
+	return data, errs
+}
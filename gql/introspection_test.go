@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ichaly/ideabase/gql/compiler"
 	"github.com/ichaly/ideabase/utl"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 )
@@ -120,7 +122,7 @@ func TestIntrospection(t *testing.T) {
 		assert.True(t, ok, "schemaData应为map[string]interface{}")
 
 		// 验证queryType
-		queryType, ok := schemaDataMap["queryType"].(map[string]string)
+		queryType, ok := schemaDataMap["queryType"].(map[string]interface{})
 		assert.True(t, ok, "结果中应包含queryType")
 		assert.Equal(t, "Query", queryType["name"])
 
@@ -173,3 +175,118 @@ func TestIntrospection(t *testing.T) {
 		assert.NotEmpty(t, fields)
 	})
 }
+
+// newCountingExecutor 创建使用计数方言的执行器，只编译SQL不访问数据库
+func newCountingExecutor(t *testing.T) *Executor {
+	meta := createMockMetadata(t)
+	executor := newMockExecutor(t, meta)
+	executor.compiler = &Compiler{meta: meta, dialect: &countingDialect{}}
+	return executor
+}
+
+func TestIntrospectionRouting(t *testing.T) {
+	executor := newCountingExecutor(t)
+	execute := func(query string, vars map[string]interface{}) gqlReply {
+		r := executor.Execute(context.Background(), query, vars, "")
+		require.Empty(t, r.Errors)
+		return r
+	}
+
+	t.Run("根字段__typename", func(t *testing.T) {
+		r := execute(`{ __typename }`, nil)
+		assert.Equal(t, map[string]interface{}{"__typename": "Query"}, r.Data)
+		assert.Zero(t, executor.compiler.dialect.(*countingDialect).builds, "只有自省字段时不访问数据库")
+	})
+
+	t.Run("字面量参数与别名", func(t *testing.T) {
+		r := execute(`{ t: __type(name: "User") { name kind __typename fields { name } } }`, nil)
+		typ := r.Data["t"].(map[string]interface{})
+		assert.Equal(t, "User", typ["name"])
+		assert.Equal(t, "__Type", typ["__typename"])
+		assert.NotContains(t, typ, "description", "只返回选择的字段")
+		assert.NotEmpty(t, typ["fields"])
+	})
+
+	t.Run("变量参数与片段", func(t *testing.T) {
+		r := execute(`query($n: String!) { __type(name: $n) { ...T } } fragment T on __Type { name }`, map[string]interface{}{"n": "Post"})
+		assert.Equal(t, map[string]interface{}{"name": "Post"}, r.Data["__type"])
+
+		r = execute(`{ __type(name: "Unknown") { name } }`, nil)
+		assert.Contains(t, r.Data, "__type")
+		assert.Nil(t, r.Data["__type"])
+	})
+
+	// 包含数据字段的查询只检查路由和编译结果
+	build := func(query string) (*planEntry, string) {
+		entry, err := executor.prepare(context.Background(), query, "", "")
+		require.NoError(t, err)
+		if entry.data == nil {
+			return entry, ""
+		}
+		sql, _, err := executor.build(entry, entry.data, nil)
+		require.NoError(t, err)
+		return entry, sql
+	}
+
+	t.Run("自省与数据字段混合", func(t *testing.T) {
+		entry, sql := build(`{ __schema { queryType { name } } ... on Query { users { __typename total } } }`)
+		require.Len(t, entry.intro, 1)
+		assert.Equal(t, "__schema", entry.intro[0].Name)
+		assert.Equal(t, `SELECT "users"`, sql, "数据字段交给编译器，__typename在编译前移除")
+	})
+
+	t.Run("字符串中的关键字不影响路由", func(t *testing.T) {
+		entry, sql := build(`{ users(where: {name: {eq: "__schema"}}) { total } }`)
+		assert.Empty(t, entry.intro)
+		assert.Equal(t, `SELECT "users"`, sql)
+	})
+}
+
+func TestIntrospectionTypename(t *testing.T) {
+	executor := newCountingExecutor(t)
+	doc, err := gqlparser.LoadQuery(executor.schema, `{
+		__typename
+		users { __typename items { ...F } }
+	} fragment F on User { id __typename }`)
+	require.Nil(t, err)
+
+	intro, data := splitIntrospection(doc.Operations[0])
+	require.Len(t, intro, 1)
+	assert.Equal(t, "__typename", intro[0].Name)
+	assert.Equal(t, "users { items { ... F id } }", strings.Join(strings.Fields(formatSelection(data.SelectionSet)), " "))
+	assert.Contains(t, formatSelection(doc.Operations[0].SelectionSet), "__typename", "原始选择集保持不变")
+
+	result := map[string]interface{}{
+		"users": map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+		}},
+	}
+	fillTypename(doc.Operations[0].SelectionSet, result)
+	users := result["users"].(map[string]interface{})
+	assert.Equal(t, "UserResult", users["__typename"])
+	for _, item := range users["items"].([]interface{}) {
+		assert.Equal(t, "User", item.(map[string]interface{})["__typename"])
+	}
+}
+
+// formatSelection 格式化选择集，片段引用只输出名称
+func formatSelection(set ast.SelectionSet) string {
+	var buf strings.Builder
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.Field:
+			buf.WriteString(v.Alias)
+			if len(v.SelectionSet) > 0 {
+				buf.WriteString(" { " + formatSelection(v.SelectionSet) + " }")
+			}
+		case *ast.FragmentSpread:
+			buf.WriteString("... " + v.Name)
+			if v.Definition != nil {
+				buf.WriteString(" " + formatSelection(v.Definition.SelectionSet))
+			}
+		}
+		buf.WriteString(" ")
+	}
+	return buf.String()
+}
@@ -0,0 +1,172 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// 查询复杂度相关错误码
+const (
+	CODE_QUERY_DEPTH_EXCEEDED = "QUERY_DEPTH_EXCEEDED"
+	CODE_QUERY_ALIAS_EXCEEDED = "QUERY_ALIAS_EXCEEDED"
+	CODE_QUERY_COST_EXCEEDED  = "QUERY_COST_EXCEEDED"
+	CODE_QUERY_LIMIT_EXCEEDED = "QUERY_LIMIT_EXCEEDED"
+)
+
+// 估算成本的上限，避免多层关联相乘溢出
+const maxEstimatedCost = 1 << 40
+
+// complexity 查询复杂度统计
+type complexity struct {
+	Depth   int
+	Aliases int
+	Cost    int64
+}
+
+// complexityWalker 遍历选择集统计复杂度
+type complexityWalker struct {
+	executor  *Executor
+	variables map[string]interface{}
+	result    complexity
+	errors    gqlerror.List
+}
+
+// checkComplexity 在编译SQL之前校验查询深度、别名数量、分页限制和估算成本
+// 超出配置的预算时返回结构化错误，extensions中包含code、maximum和actual
+func (my *Executor) checkComplexity(operation *ast.OperationDefinition, variables map[string]interface{}) gqlerror.List {
+	w := &complexityWalker{executor: my, variables: variables}
+	w.walk(operation.SelectionSet, ast.Path{}, 1, 0, false)
+
+	cfg := my.metadata.cfg.Executor.Limit
+	if cfg.MaxDepth > 0 && w.result.Depth > cfg.MaxDepth {
+		w.errors = append(w.errors, newLimitError(CODE_QUERY_DEPTH_EXCEEDED, "查询深度", cfg.MaxDepth, int64(w.result.Depth)))
+	}
+	if cfg.MaxAliases > 0 && w.result.Aliases > cfg.MaxAliases {
+		w.errors = append(w.errors, newLimitError(CODE_QUERY_ALIAS_EXCEEDED, "别名数量", cfg.MaxAliases, int64(w.result.Aliases)))
+	}
+	if cfg.MaxCost > 0 && w.result.Cost > int64(cfg.MaxCost) {
+		w.errors = append(w.errors, newLimitError(CODE_QUERY_COST_EXCEEDED, "查询成本", cfg.MaxCost, w.result.Cost))
+	}
+	return w.errors
+}
+
+// walk 递归统计选择集，multiplier为当前层级展开的行数，wrapped表示父字段是分页结果类型
+func (my *complexityWalker) walk(set ast.SelectionSet, path ast.Path, multiplier int64, depth int, wrapped bool) {
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.InlineFragment:
+			my.walk(v.SelectionSet, path, multiplier, depth, wrapped)
+		case *ast.FragmentSpread:
+			if v.Definition != nil {
+				my.walk(v.Definition.SelectionSet, path, multiplier, depth, wrapped)
+			}
+		case *ast.Field:
+			my.field(v, path, multiplier, depth, wrapped)
+		}
+	}
+}
+
+func (my *complexityWalker) field(f *ast.Field, path ast.Path, multiplier int64, depth int, wrapped bool) {
+	// 与常见的深度限制实现一致，不统计自省字段
+	if strings.HasPrefix(f.Name, "__") || f.Definition == nil {
+		return
+	}
+	if depth+1 > my.result.Depth {
+		my.result.Depth = depth + 1
+	}
+	if f.Alias != "" && f.Alias != f.Name {
+		my.result.Aliases++
+	}
+	path = append(append(ast.Path{}, path...), ast.PathName(f.Alias))
+
+	class, result := my.lookup(f.Definition.Type.Name())
+	isList := f.Definition.Type.Elem != nil
+	// 分页结果中的items已在外层计入成本
+	if class != nil && !(wrapped && isList) {
+		fan := int64(1)
+		if isList || result || f.Definition.Arguments.ForName(LIMIT) != nil {
+			fan = my.fanOut(f, path)
+		}
+		multiplier = min(multiplier*fan, maxEstimatedCost)
+		weight := int64(class.Cost)
+		if weight <= 0 {
+			weight = 1
+		}
+		my.result.Cost = min(my.result.Cost+weight*multiplier, maxEstimatedCost)
+	}
+	my.walk(f.SelectionSet, path, multiplier, depth+1, result)
+}
+
+// lookup 查找字段类型对应的类，分页结果类型返回其元素类
+func (my *complexityWalker) lookup(typeName string) (*protocol.Class, bool) {
+	nodes := my.executor.metadata.Nodes
+	if class, ok := nodes[typeName]; ok {
+		return class, false
+	}
+	if name, ok := strings.CutSuffix(typeName, SUFFIX_RESULT); ok {
+		if class, ok := nodes[name]; ok {
+			return class, true
+		}
+	}
+	return nil, false
+}
+
+// fanOut 计算字段展开的行数，优先使用请求的limit/first/last，其次为默认和最大分页限制
+func (my *complexityWalker) fanOut(f *ast.Field, path ast.Path) int64 {
+	cfg := my.executor.metadata.cfg.Schema
+	args := f.ArgumentMap(my.variables)
+	var fan int64
+	for _, name := range []string{LIMIT, FIRST, LAST} {
+		n, ok := toInt64(args[name])
+		if !ok {
+			continue
+		}
+		if cfg.MaxLimit > 0 && n > int64(cfg.MaxLimit) {
+			err := newLimitError(CODE_QUERY_LIMIT_EXCEEDED, "参数"+name, cfg.MaxLimit, n)
+			err.Path = path
+			err.Locations = []gqlerror.Location{{Line: f.Position.Line, Column: f.Position.Column}}
+			my.errors = append(my.errors, err)
+		}
+		fan = max(fan, n)
+	}
+	if fan > 0 {
+		return fan
+	}
+	for _, n := range []int{cfg.DefaultLimit, cfg.MaxLimit} {
+		if n > 0 {
+			return int64(n)
+		}
+	}
+	return 1
+}
+
+// toInt64 将参数值转换为整数
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// newLimitError 创建超出限制的结构化错误
+func newLimitError(code, subject string, maximum int, actual int64) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("%s%d超过了允许的最大值%d", subject, actual, maximum),
+		Extensions: map[string]interface{}{
+			"code":    code,
+			"maximum": maximum,
+			"actual":  actual,
+		},
+	}
+}
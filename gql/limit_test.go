@@ -0,0 +1,93 @@
+package gql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestLimitComplexity(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Schema.DefaultLimit = 10
+	meta.cfg.Schema.MaxLimit = 100
+	meta.Nodes["User"].Cost = 3
+	executor := newMockExecutor(t, meta)
+
+	check := func(query string, vars map[string]interface{}) (complexity, gqlerror.List) {
+		doc, err := gqlparser.LoadQuery(executor.schema, query)
+		require.Nil(t, err)
+		w := &complexityWalker{executor: executor, variables: vars}
+		w.walk(doc.Operations[0].SelectionSet, ast.Path{}, 1, 0, false)
+		return w.result, w.errors
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		want  complexity
+	}{
+		{
+			name:  "默认分页",
+			query: `{ posts { items { id title } total } }`,
+			want:  complexity{Depth: 3, Cost: 10},
+		},
+		{
+			name:  "显式分页与类权重",
+			query: `{ users(limit: 20) { items { id } } }`,
+			want:  complexity{Depth: 3, Cost: 60},
+		},
+		{
+			name:  "变量分页",
+			query: `query($n: Int) { users(first: $n) { items { id } } }`,
+			vars:  map[string]interface{}{"n": 5},
+			want:  complexity{Depth: 3, Cost: 15},
+		},
+		{
+			name:  "别名与片段",
+			query: `{ a: posts { ...P } b: posts { ...P } } fragment P on PostResult { items { id } }`,
+			want:  complexity{Depth: 3, Aliases: 2, Cost: 20},
+		},
+		{
+			name:  "忽略自省字段",
+			query: `{ __typename posts { __typename items { __typename id } } }`,
+			want:  complexity{Depth: 3, Cost: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := check(tt.query, tt.vars)
+			assert.Empty(t, errs)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("超过最大分页", func(t *testing.T) {
+		_, errs := check(`{ users { items { id } } posts(limit: 500) { items { id } } }`, nil)
+		require.Len(t, errs, 1)
+		assert.Equal(t, CODE_QUERY_LIMIT_EXCEEDED, errs[0].Extensions["code"])
+		assert.Equal(t, ast.Path{ast.PathName("posts")}, errs[0].Path)
+		assert.Equal(t, 100, errs[0].Extensions["maximum"])
+		assert.Equal(t, int64(500), errs[0].Extensions["actual"])
+	})
+
+	t.Run("执行前拒绝超出预算的查询", func(t *testing.T) {
+		meta.cfg.Executor.Limit.MaxDepth = 2
+		meta.cfg.Executor.Limit.MaxAliases = 1
+		meta.cfg.Executor.Limit.MaxCost = 50
+		r := executor.Execute(context.Background(), `{ a: users { items { id } } b: users { items { id } } }`, nil, "")
+		require.Len(t, r.Errors, 3)
+		var codes []interface{}
+		for _, err := range r.Errors {
+			codes = append(codes, err.Extensions["code"])
+		}
+		assert.Equal(t, []interface{}{CODE_QUERY_DEPTH_EXCEEDED, CODE_QUERY_ALIAS_EXCEEDED, CODE_QUERY_COST_EXCEEDED}, codes)
+		assert.Equal(t, int64(60), r.Errors[2].Extensions["actual"])
+		assert.Nil(t, r.Data)
+	})
+}
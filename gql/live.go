@@ -0,0 +1,126 @@
+package gql
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/ichaly/ideabase/utl"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// 实时查询相关常量
+const (
+	DIRECTIVE_LIVE = "live"
+	LIVE_PATCH     = "patch"
+
+	DESC_LIVE = "实时查询，涉及的表发生变更时重新执行并推送结果，patch为true时后续结果以JSON Patch推送"
+)
+
+// JSON Patch操作类型（RFC 6902）
+const (
+	patchAdd     = "add"
+	patchRemove  = "remove"
+	patchReplace = "replace"
+)
+
+// patchOperation JSON Patch操作，remove操作的value会被客户端忽略
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// renderLive 渲染@live指令
+func (my *Renderer) renderLive() error {
+	my.writeLine("# ", DESC_LIVE)
+	my.writeLine("directive @", DIRECTIVE_LIVE, "(", LIVE_PATCH, ": ", SCALAR_BOOLEAN, " = false) on QUERY")
+	my.writeLine()
+	return nil
+}
+
+// liveOptions 解析查询操作上的@live指令，返回是否实时查询以及是否以JSON Patch推送
+func liveOptions(operation *ast.OperationDefinition, variables map[string]interface{}) (bool, bool) {
+	if operation.Operation != ast.Query {
+		return false, false
+	}
+	d := operation.Directives.ForName(DIRECTIVE_LIVE)
+	if d == nil {
+		return false, false
+	}
+	patch, _ := d.ArgumentMap(variables)[LIVE_PATCH].(bool)
+	return true, patch
+}
+
+// mutationChanges 根据变更操作的根字段推导发生变更的表
+// 根字段按create/update/delete前缀加类名命名，见renderMutation
+func (my *Executor) mutationChanges(operation *ast.OperationDefinition) []event.TableChange {
+	actions := []struct{ prefix, action string }{
+		{CREATE, event.ActionCreate},
+		{UPDATE, event.ActionUpdate},
+		{DELETE, event.ActionDelete},
+	}
+	var changes []event.TableChange
+	for _, s := range operation.SelectionSet {
+		field, ok := s.(*ast.Field)
+		if !ok {
+			continue
+		}
+		for _, a := range actions {
+			class, ok := my.metadata.Nodes[strings.TrimPrefix(field.Name, a.prefix)]
+			if !strings.HasPrefix(field.Name, a.prefix) || !ok || class.Virtual || class.Table == "" {
+				continue
+			}
+			changes = append(changes, event.TableChange{Table: class.Table, Action: a.action})
+			break
+		}
+	}
+	return changes
+}
+
+// diffPatch 比较两份JSON文档，生成将from变换为to的JSON Patch
+// 对象按键逐层比较；数组长度一致时逐项比较，否则整体替换
+func diffPatch(from, to interface{}, path string) []patchOperation {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var ops []patchOperation
+		for _, k := range utl.SortKeys(f) {
+			if _, exists := t[k]; !exists {
+				ops = append(ops, patchOperation{Op: patchRemove, Path: path + "/" + escapePointer(k)})
+			}
+		}
+		for _, k := range utl.SortKeys(t) {
+			p := path + "/" + escapePointer(k)
+			if v, exists := f[k]; exists {
+				ops = append(ops, diffPatch(v, t[k], p)...)
+			} else {
+				ops = append(ops, patchOperation{Op: patchAdd, Path: p, Value: t[k]})
+			}
+		}
+		return ops
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok || len(f) != len(t) {
+			break
+		}
+		var ops []patchOperation
+		for i := range f {
+			ops = append(ops, diffPatch(f[i], t[i], path+"/"+strconv.Itoa(i))...)
+		}
+		return ops
+	}
+	if reflect.DeepEqual(from, to) {
+		return nil
+	}
+	return []patchOperation{{Op: patchReplace, Path: path, Value: to}}
+}
+
+// escapePointer 按JSON Pointer规则转义路径片段
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,134 @@
+package gql
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestLiveDiffPatch(t *testing.T) {
+	var from, to interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"data":{"users":{"total":2,"items":[{"id":"1","name":"Tom"},{"id":"2","name":"Jerry"}]},"a/b":1,"old":true}}`), &from))
+	require.NoError(t, json.Unmarshal([]byte(`{"data":{"users":{"total":3,"items":[{"id":"1","name":"Tom"},{"id":"2","name":"Spike"}]},"a/b":null,"new":[1]}}`), &to))
+
+	assert.Equal(t, []patchOperation{
+		{Op: patchRemove, Path: "/data/old"},
+		{Op: patchReplace, Path: "/data/a~1b", Value: nil},
+		{Op: patchAdd, Path: "/data/new", Value: []interface{}{float64(1)}},
+		{Op: patchReplace, Path: "/data/users/items/1/name", Value: "Spike"},
+		{Op: patchReplace, Path: "/data/users/total", Value: float64(3)},
+	}, diffPatch(from, to, ""))
+
+	// 数组长度变化时整体替换
+	assert.Equal(t, []patchOperation{{Op: patchReplace, Path: "/items", Value: []interface{}{1, 2}}},
+		diffPatch(map[string]interface{}{"items": []interface{}{1}}, map[string]interface{}{"items": []interface{}{1, 2}}, ""))
+	assert.Empty(t, diffPatch(to, to, ""))
+}
+
+func TestLiveOptions(t *testing.T) {
+	executor := newMockExecutor(t, createMockMetadata(t))
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		live      bool
+		patch     bool
+	}{
+		{name: "普通查询", query: `query { users { total } }`},
+		{name: "实时查询", query: `query @live { users { total } }`, live: true},
+		{name: "补丁推送", query: `query @live(patch: true) { users { total } }`, live: true, patch: true},
+		{name: "变量控制", query: `query ($p: Boolean) @live(patch: $p) { users { total } }`, variables: map[string]interface{}{"p": true}, live: true, patch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := gqlparser.LoadQuery(executor.schema, tt.query)
+			require.Nil(t, err)
+			live, patch := liveOptions(doc.Operations[0], tt.variables)
+			assert.Equal(t, tt.live, live)
+			assert.Equal(t, tt.patch, patch)
+		})
+	}
+
+	// @live只能用于查询
+	_, err := gqlparser.LoadQuery(executor.schema, `mutation @live { deleteUser(id: 1) }`)
+	assert.NotNil(t, err)
+}
+
+func TestLiveMutationChanges(t *testing.T) {
+	executor := newMockExecutor(t, createMockMetadata(t))
+
+	doc, err := gqlparser.LoadQuery(executor.schema, `mutation {
+		deleteUser(id: 1)
+		updatePost(id: 1, input: { title: "hello" }) { id }
+	}`)
+	require.Nil(t, err)
+	assert.Equal(t, []event.TableChange{
+		{Table: "users", Action: event.ActionDelete},
+		{Table: "posts", Action: event.ActionUpdate},
+	}, executor.mutationChanges(doc.Operations[0]))
+
+	// 未配置事件总线时直接分发给本实例的订阅
+	notify, cancel := executor.broker.register(map[string]bool{"posts": true})
+	defer cancel()
+	executor.broker.publish(context.Background(), executor.mutationChanges(doc.Operations[0]))
+	assert.Len(t, notify, 1)
+}
+
+func TestLiveServerSentEvents(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Executor.Subscription.KeepAlive = 50 * time.Millisecond
+	executor := newMockExecutor(t, meta)
+
+	app := fiber.New()
+	executor.Bind(app.Group(executor.Path()))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	defer func() { _ = app.Shutdown() }()
+
+	post := func(query string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, "http://"+ln.Addr().String()+executor.Path(), strings.NewReader(`{"query":"`+query+`"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", sseContentType)
+		client := http.Client{Timeout: 3 * time.Second}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 实时查询推送首次结果后保持连接
+	resp := post(`query @live { __typename }`)
+	assert.Equal(t, sseContentType, resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: next\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: {\"data\":{\"__typename\":\"Query\"}}\n", line)
+	_ = resp.Body.Close()
+
+	// 普通查询推送一次结果后发送complete
+	resp = post(`query { __typename }`)
+	body := new(strings.Builder)
+	_, _ = bufio.NewReader(resp.Body).WriteTo(body)
+	_ = resp.Body.Close()
+	assert.True(t, strings.HasSuffix(body.String(), "event: complete\ndata: \n\n"), body.String())
+
+	// 查询解析失败时直接返回JSON错误
+	resp = post(`query { unknown }`)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+	_ = resp.Body.Close()
+}
@@ -1,6 +1,8 @@
 package gql
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/ichaly/ideabase/gql/internal"
 	"os"
@@ -27,9 +29,16 @@ func init() {
 
 // Metadata 表示GraphQL元数据
 type Metadata struct {
-	k   *std.Konfig
-	db  *gorm.DB
-	cfg *internal.Config
+	k    *std.Konfig
+	db   *gorm.DB
+	cfg  *internal.Config
+	opts []MetadataOption
+
+	// 加载过程中收集的问题，严格模式下统一校验
+	problems []string
+
+	// 角色视图对应的角色，完整元数据为空
+	role string
 
 	// 统一索引: 支持类名、表名、原始表名查找
 	Nodes   map[string]*protocol.Class `json:"nodes"`
@@ -60,6 +69,18 @@ func WithLoader(loader protocol.Loader) MetadataOption {
 	}
 }
 
+// WithEntities 注册需要解析的GORM实体，通常传入ioc.Entity注册的entity分组
+func WithEntities(entities ...interface{}) MetadataOption {
+	return func(opts *metadataOptions) {
+		for _, l := range opts.loaders {
+			if loader, ok := l.(*metadata.GormLoader); ok {
+				loader.AddEntities(entities...)
+				return
+			}
+		}
+	}
+}
+
 // WithoutLoader 移除指定名称的Loader
 func WithoutLoader(names ...string) MetadataOption {
 	return func(opts *metadataOptions) {
@@ -98,27 +119,13 @@ func (my *HookedLoader) Load(h protocol.Hoster) error {
 
 // NewMetadata 策略模式重构，支持Loader注册与优先级排序
 func NewMetadata(k *std.Konfig, d *gorm.DB, opts ...MetadataOption) (*Metadata, error) {
-	cfg := &internal.Config{Schema: internal.SchemaConfig{TypeMapping: dataTypes}}
-
-	// 设置默认配置
-	k.SetDefault("schema.schema", "public")
-	k.SetDefault("schema.default-limit", 10)
-	k.SetDefault("schema.table-prefix", []string{})
-	k.SetDefault("schema.exclude-tables", []string{})
-	k.SetDefault("schema.exclude-fields", []string{})
-
-	// 设置元数据默认配置
-	k.SetDefault("metadata.file", "cfg/metadata.{mode}.json")
-	k.SetDefault("metadata.use-camel", true)
-	k.SetDefault("metadata.use-singular", true)
-	k.SetDefault("metadata.show-through", true)
-
-	if err := k.Unmarshal(cfg); err != nil {
+	cfg, err := newMetadataConfig(k)
+	if err != nil {
 		return nil, err
 	}
 
 	my := &Metadata{
-		k: k, db: d, cfg: cfg,
+		k: k, db: d, cfg: cfg, opts: opts,
 		Nodes:   make(map[string]*protocol.Class),
 		Version: time.Now().Format("20060102150405"),
 	}
@@ -131,9 +138,11 @@ func NewMetadata(k *std.Konfig, d *gorm.DB, opts ...MetadataOption) (*Metadata,
 		return nil
 	}
 	defaultLoaders := []protocol.Loader{
+		metadata.NewGormLoader(cfg, d),
 		&HookedLoader{Loader: metadata.NewPgsqlLoader(cfg, d), afterLoad: after},
 		&HookedLoader{Loader: metadata.NewMysqlLoader(cfg, d), afterLoad: after},
 		metadata.NewFileLoader(cfg),
+		metadata.NewSdlLoader(cfg),
 		metadata.NewConfigLoader(cfg),
 	}
 	options := &metadataOptions{loaders: defaultLoaders}
@@ -154,6 +163,7 @@ func NewMetadata(k *std.Konfig, d *gorm.DB, opts ...MetadataOption) (*Metadata,
 		if loader.Support() {
 			if err := loader.Load(my); err != nil {
 				log.Warn().Err(err).Str("loader", loader.Name()).Msg("加载器执行失败")
+				my.Report("加载器%s执行失败: %v", loader.Name(), err)
 			}
 		}
 	}
@@ -161,9 +171,93 @@ func NewMetadata(k *std.Konfig, d *gorm.DB, opts ...MetadataOption) (*Metadata,
 	my.normalize()
 	// 统一关系处理
 	my.processRelations()
+	// 校验最终元数据，严格模式下存在问题时返回错误
+	if err := my.validate(); err != nil {
+		if cfg.Metadata.Strict {
+			return nil, err
+		}
+		log.Warn().Msg(err.Error())
+	}
 	return my, nil
 }
 
+// newMetadataConfig 设置默认配置并解析元数据相关配置
+func newMetadataConfig(k *std.Konfig) (*internal.Config, error) {
+	cfg := &internal.Config{Schema: internal.SchemaConfig{TypeMapping: dataTypes}}
+
+	// 设置默认配置
+	k.SetDefault("schema.schema", "public")
+	k.SetDefault("schema.default-limit", 10)
+	k.SetDefault("schema.max-limit", 1000)
+	k.SetDefault("schema.table-prefix", []string{})
+	k.SetDefault("schema.exclude-tables", []string{})
+	k.SetDefault("schema.exclude-fields", []string{})
+	k.SetDefault("schema.relay", false)
+
+	// 设置元数据默认配置
+	k.SetDefault("metadata.file", "cfg/metadata.{mode}.json")
+	k.SetDefault("metadata.use-camel", true)
+	k.SetDefault("metadata.use-singular", true)
+	k.SetDefault("metadata.show-through", true)
+	k.SetDefault("metadata.reload.watch", false)
+	k.SetDefault("metadata.reload.interval", 0)
+	k.SetDefault("metadata.roles.claim", "role")
+	k.SetDefault("metadata.roles.default", "user")
+	k.SetDefault("metadata.roles.anonymous", "anonymous")
+
+	// 设置执行器默认配置
+	k.SetDefault("executor.timeout", "30s")
+	k.SetDefault("executor.subscription.init-timeout", "10s")
+	k.SetDefault("executor.subscription.keep-alive", "15s")
+	k.SetDefault("executor.persisted.ttl", "24h")
+	k.SetDefault("executor.cache-control.max-age", "0s")
+	k.SetDefault("executor.cache-control.private", false)
+	k.SetDefault("executor.trusted.enable", false)
+	k.SetDefault("executor.trusted.manifest", "cfg/trusted.json")
+	k.SetDefault("executor.batch.max-size", 10)
+	k.SetDefault("executor.batch.concurrency", 4)
+	k.SetDefault("executor.limit.max-depth", 12)
+	k.SetDefault("executor.limit.max-aliases", 30)
+	k.SetDefault("executor.limit.max-cost", 10000)
+	k.SetDefault("executor.plan.size", 1000)
+	k.SetDefault("executor.tracing.enable", false)
+	k.SetDefault("executor.metrics.max-operations", 100)
+
+	if err := k.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+	if !k.IsSet("metadata.strict") {
+		cfg.Metadata.Strict = cfg.IsProd()
+	}
+	return cfg, nil
+}
+
+// Reload 使用相同的配置源和Loader重新构建一份元数据，原实例保持不变
+func (my *Metadata) Reload() (*Metadata, error) {
+	return NewMetadata(my.k, my.db, my.opts...)
+}
+
+// fingerprint 计算元数据、schema与执行器配置的指纹，用于判断重建后是否有实际变化
+// 执行器配置只影响请求处理，变化时同样需要替换快照
+func (my *Metadata) fingerprint() string {
+	if my == nil {
+		return ""
+	}
+	nodes := make(map[string]*protocol.Class)
+	for key, class := range my.Nodes {
+		if key == class.Name {
+			nodes[key] = class
+		}
+	}
+	var schema, roles, executor interface{}
+	if my.cfg != nil {
+		schema, roles, executor = my.cfg.Schema, my.cfg.Metadata.Roles, my.cfg.Executor
+	}
+	data, _ := json.Marshal(map[string]interface{}{"nodes": nodes, "schema": schema, "roles": roles, "executor": executor})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (my *Metadata) PutNode(className string, node *protocol.Class) error {
 	if node == nil || node.Name == "" {
 		return nil
@@ -172,6 +266,11 @@ func (my *Metadata) PutNode(className string, node *protocol.Class) error {
 	return nil
 }
 
+// Report 记录加载过程中发现的问题
+func (my *Metadata) Report(format string, args ...interface{}) {
+	my.problems = append(my.problems, fmt.Sprintf(format, args...))
+}
+
 func (my *Metadata) GetNode(name string) (*protocol.Class, bool) {
 	n, ok := my.Nodes[name]
 	return n, ok
@@ -327,6 +426,7 @@ func (my *Metadata) processRelations() {
 			if targetClass == nil {
 				log.Warn().Str("class", class.Name).Str("field", field.Name).
 					Str("targetClass", targetClassName).Msg("关系目标类不存在")
+				my.Report("关系%s.%s的目标类%s不存在", class.Name, field.Name, targetClassName)
 				continue
 			}
 
@@ -336,6 +436,7 @@ func (my *Metadata) processRelations() {
 				log.Warn().Str("class", class.Name).Str("field", field.Name).
 					Str("targetClass", targetClassName).Str("targetField", relation.TargetFiled).
 					Msg("关系目标字段不存在")
+				my.Report("关系%s.%s的目标字段%s.%s不存在", class.Name, field.Name, targetClassName, relation.TargetFiled)
 				continue
 			}
 
@@ -403,8 +504,24 @@ func (my *Metadata) processRelations() {
 		}
 	}
 
+	// 已显式声明的关系字段(如SDL中的对象字段)，不再按默认规则重复生成
+	declared := make(map[string]bool)
+	for className, class := range my.Nodes {
+		if className != class.Name {
+			continue
+		}
+		for _, field := range class.Fields {
+			if field.Virtual && !field.IsThrough {
+				declared[fmt.Sprintf("%s:%s:%t", className, field.Type, field.IsList)] = true
+			}
+		}
+	}
+
 	// 第二阶段：创建所有关系字段
 	for _, info := range fieldsToCreate {
+		if declared[fmt.Sprintf("%s:%s:%t", info.SourceClass, info.TargetClass, info.IsList)] {
+			continue
+		}
 		if class := my.Nodes[info.SourceClass]; class != nil {
 			// 如果字段不存在，则创建
 			if _, has := class.Fields[info.FieldName]; !has {
@@ -492,6 +609,7 @@ func (my *Metadata) normalize() error {
 		}
 
 		fields := make(map[string]*protocol.Field)
+		derived := make(map[string]*protocol.Field)
 		for fieldKey, field := range class.Fields {
 			// 跳过需要忽略的字段
 			if field.Column != "" && lo.IndexOf(config.ExcludeFields, field.Column) > -1 {
@@ -504,11 +622,11 @@ func (my *Metadata) normalize() error {
 					if field.Name == field.Column {
 						field.Name = canonName
 					}
-					fields[field.Name] = field
+					derived[field.Name] = field
 				} else if field.Name == canonName {
-					fields[field.Column] = field
+					derived[field.Column] = field
 				} else if fieldKey != field.Name {
-					fields[field.Name] = field
+					derived[field.Name] = field
 				}
 			}
 			// 始终用原始字段名做key
@@ -518,6 +636,12 @@ func (my *Metadata) normalize() error {
 				relations = append(relations, field)
 			}
 		}
+		// 派生的key不覆盖原始字段名，驼峰转换后重名的字段都保留，由严格校验报告
+		for key, field := range derived {
+			if _, ok := fields[key]; !ok {
+				fields[key] = field
+			}
+		}
 		class.Fields = fields
 
 		// 如果是表索引且表名和类名一致，则用标准名赋值并用标准名做key
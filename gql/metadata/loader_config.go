@@ -14,7 +14,8 @@ import (
 // ConfigLoader 配置元数据加载器
 // 实现Loader接口
 type ConfigLoader struct {
-	cfg *internal.Config
+	cfg      *internal.Config
+	problems []string
 }
 
 // NewConfigLoader 创建配置加载器
@@ -32,6 +33,16 @@ func (my *ConfigLoader) Support() bool {
 
 // Load 从配置加载元数据
 func (my *ConfigLoader) Load(h protocol.Hoster) error {
+	my.problems = nil
+	defer func() {
+		// 上报配置中引用的未知列
+		if r, ok := h.(protocol.Reporter); ok {
+			for _, p := range my.problems {
+				r.Report("%s", p)
+			}
+		}
+	}()
+
 	// 1. 分组排序
 	var tableClasses, canonClasses, overrideClasses, aliasClasses, virtualClasses []string
 	for className, classConfig := range my.cfg.Metadata.Classes {
@@ -127,6 +138,26 @@ func (my *ConfigLoader) buildClassFromConfig(className string, classConfig *inte
 	if len(classConfig.PrimaryKeys) > 0 {
 		newClass.PrimaryKeys = classConfig.PrimaryKeys
 	}
+	if classConfig.Cost > 0 {
+		newClass.Cost = classConfig.Cost
+	}
+	if len(classConfig.Permissions) > 0 {
+		newClass.Permissions = make(map[string]*protocol.Permission, len(classConfig.Permissions))
+		for role, p := range classConfig.Permissions {
+			if p == nil {
+				continue
+			}
+			newClass.Permissions[role] = &protocol.Permission{
+				Operations:    p.Operations,
+				Filter:        p.Filter,
+				IncludeFields: p.IncludeFields,
+				ExcludeFields: p.ExcludeFields,
+			}
+		}
+	}
+	if baseClass != nil && !isVirtual {
+		my.checkColumns(className, classConfig, baseClass)
+	}
 	my.applyFieldFilter(newClass, classConfig)
 	if err := my.applyFieldConfig(newClass, classConfig.Fields); err != nil {
 		return nil, err
@@ -134,6 +165,17 @@ func (my *ConfigLoader) buildClassFromConfig(className string, classConfig *inte
 	return newClass, nil
 }
 
+// checkColumns 检查配置字段引用的列是否存在于已加载的表结构中
+func (my *ConfigLoader) checkColumns(className string, classConfig *internal.ClassConfig, baseClass *protocol.Class) {
+	for fieldName, fieldConfig := range classConfig.Fields {
+		if fieldConfig.Column == "" || baseClass.Fields[fieldName] != nil || baseClass.Fields[fieldConfig.Column] != nil {
+			continue
+		}
+		my.problems = append(my.problems, fmt.Sprintf("配置字段%s.%s引用了表%s中不存在的列%s",
+			className, fieldName, classConfig.Table, fieldConfig.Column))
+	}
+}
+
 // 应用字段过滤
 func (my *ConfigLoader) applyFieldFilter(class *protocol.Class, config *internal.ClassConfig) {
 	if len(config.IncludeFields) > 0 {
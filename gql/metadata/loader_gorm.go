@@ -0,0 +1,231 @@
+package metadata
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// GormLoader 实体元数据加载器
+// 解析通过ioc.Entity注册的GORM模型，在数据库尚未建表时也能生成与Go模型一致的schema
+type GormLoader struct {
+	cfg      *internal.Config
+	db       *gorm.DB
+	entities []interface{}
+}
+
+// NewGormLoader 创建实体加载器，db为空时使用默认命名策略解析模型
+func NewGormLoader(cfg *internal.Config, db *gorm.DB, entities ...interface{}) *GormLoader {
+	return &GormLoader{cfg: cfg, db: db, entities: entities}
+}
+
+func (my *GormLoader) Name() string  { return LoaderGorm }
+func (my *GormLoader) Priority() int { return 50 }
+
+// Support 注册了实体时启用
+func (my *GormLoader) Support() bool {
+	return len(my.entities) > 0
+}
+
+// AddEntities 追加需要解析的实体
+func (my *GormLoader) AddEntities(entities ...interface{}) {
+	my.entities = append(my.entities, entities...)
+}
+
+// Load 解析实体生成元数据
+// 1. 使用gorm.Statement.Parse解析模型，复用GORM的命名策略和标签规则
+// 2. 列转换为字段，主键、唯一约束、注释与AutoMigrate建表结果保持一致
+// 3. 根据belongs-to、has-one/has-many、many2many关联建立关系
+// 4. 实现std.Describer的实体使用Description()作为类描述
+func (my *GormLoader) Load(h protocol.Hoster) error {
+	db := my.db
+	if db == nil {
+		var err error
+		if db, err = gorm.Open(nil, &gorm.Config{}); err != nil {
+			return fmt.Errorf("初始化实体解析器失败: %w", err)
+		}
+	}
+
+	schemas := make([]*schema.Schema, 0, len(my.entities))
+	classMap := make(map[string]*protocol.Class)
+	for _, entity := range my.entities {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(entity); err != nil {
+			return fmt.Errorf("解析实体%T失败: %w", entity, err)
+		}
+		s := stmt.Schema
+		if s == nil || s.Table == "" {
+			continue
+		}
+		schemas = append(schemas, s)
+		classMap[s.Table] = buildClassFromSchema(s, describe(entity))
+	}
+
+	// 关系在全部实体解析完成后处理，确保关联双方都已存在
+	for _, s := range schemas {
+		for _, rel := range s.Relationships.Relations {
+			applyGormRelation(classMap, rel)
+		}
+	}
+
+	for table, class := range classMap {
+		if err := h.PutNode(table, class); err != nil {
+			return fmt.Errorf("注入Hoster失败: %w", err)
+		}
+	}
+	log.Info().Int("classes", len(classMap)).Msg("从实体加载元数据完成")
+	return nil
+}
+
+// buildClassFromSchema 根据GORM模型结构构建类，与数据库加载器一致以表名和列名作为初始名称
+func buildClassFromSchema(s *schema.Schema, description string) *protocol.Class {
+	class := &protocol.Class{
+		Name:        s.Table,
+		Table:       s.Table,
+		Description: description,
+		Fields:      make(map[string]*protocol.Field),
+		PrimaryKeys: []string{},
+	}
+	for _, f := range s.Fields {
+		// 关联字段和忽略的字段没有对应列
+		if f.DBName == "" {
+			continue
+		}
+		class.Fields[f.DBName] = &protocol.Field{
+			Name:        f.DBName,
+			Column:      f.DBName,
+			Type:        gormDataType(f),
+			Nullable:    !f.NotNull && !f.PrimaryKey,
+			IsPrimary:   f.PrimaryKey,
+			IsUnique:    f.PrimaryKey || f.Unique,
+			Description: f.Comment,
+		}
+	}
+	for _, f := range s.PrimaryFields {
+		class.PrimaryKeys = append(class.PrimaryKeys, f.DBName)
+	}
+	return class
+}
+
+// applyGormRelation 将GORM关联转换为关系，关系记录在外键字段上
+// 反向关系由processRelations统一推导，这里只记录正向关系
+func applyGormRelation(classMap map[string]*protocol.Class, rel *schema.Relationship) {
+	switch rel.Type {
+	case schema.BelongsTo, schema.HasOne, schema.HasMany:
+		for _, ref := range rel.References {
+			// 多态关联没有主键引用
+			if ref.PrimaryKey == nil || ref.ForeignKey == nil {
+				continue
+			}
+			source, target := ref.ForeignKey.Schema, ref.PrimaryKey.Schema
+			sourceClass, targetClass := classMap[source.Table], classMap[target.Table]
+			if sourceClass == nil || targetClass == nil {
+				continue
+			}
+			field := sourceClass.Fields[ref.ForeignKey.DBName]
+			if field == nil || field.Relation != nil {
+				continue
+			}
+			field.Relation = &protocol.Relation{
+				SourceClass: source.Table,
+				SourceFiled: ref.ForeignKey.DBName,
+				TargetClass: target.Table,
+				TargetFiled: ref.PrimaryKey.DBName,
+				Type:        lo.Ternary(source.Table == target.Table, protocol.RECURSIVE, protocol.MANY_TO_ONE),
+			}
+		}
+	case schema.Many2Many:
+		if rel.JoinTable == nil {
+			return
+		}
+		var sourceKey, targetKey, sourceColumn, targetColumn string
+		for _, ref := range rel.References {
+			if ref.PrimaryKey == nil || ref.ForeignKey == nil {
+				continue
+			}
+			if ref.OwnPrimaryKey {
+				sourceKey, sourceColumn = ref.ForeignKey.DBName, ref.PrimaryKey.DBName
+			} else {
+				targetKey, targetColumn = ref.ForeignKey.DBName, ref.PrimaryKey.DBName
+			}
+		}
+		sourceClass, targetClass := classMap[rel.Schema.Table], classMap[rel.FieldSchema.Table]
+		if sourceClass == nil || targetClass == nil || sourceKey == "" || targetKey == "" {
+			return
+		}
+		field := sourceClass.Fields[sourceColumn]
+		if field == nil || !field.IsPrimary {
+			return
+		}
+		field.Relation = &protocol.Relation{
+			SourceClass: rel.Schema.Table,
+			SourceFiled: sourceColumn,
+			TargetClass: rel.FieldSchema.Table,
+			TargetFiled: targetColumn,
+			Type:        protocol.MANY_TO_MANY,
+			Through: &protocol.Through{
+				TableName: rel.JoinTable.Table,
+				SourceKey: sourceKey,
+				TargetKey: targetKey,
+			},
+		}
+		// 中间表未单独注册为实体时根据关联自动生成
+		if _, ok := classMap[rel.JoinTable.Table]; !ok {
+			classMap[rel.JoinTable.Table] = buildClassFromSchema(rel.JoinTable, "")
+		}
+		classMap[rel.JoinTable.Table].IsThrough = true
+	}
+}
+
+// gormDataType 将GORM数据类型转换为类型映射中的数据库类型名称
+func gormDataType(f *schema.Field) string {
+	switch f.DataType {
+	case schema.Bool:
+		return "boolean"
+	case schema.Int, schema.Uint:
+		if f.Size > 32 {
+			return "bigint"
+		}
+		return "integer"
+	case schema.Float:
+		if f.Size == 32 {
+			return "real"
+		}
+		return "double precision"
+	case schema.String:
+		return "varchar"
+	case schema.Time:
+		return "timestamp with time zone"
+	case schema.Bytes:
+		return "binary"
+	}
+	// type标签指定的类型，如varchar(64)、jsonb
+	dataType := strings.ToLower(strings.TrimSpace(string(f.DataType)))
+	if i := strings.IndexByte(dataType, '('); i > 0 && dataType != "tinyint(1)" {
+		dataType = strings.TrimSpace(dataType[:i])
+	}
+	return dataType
+}
+
+// describe 读取实体的描述，兼容值和指针接收者
+func describe(entity interface{}) string {
+	if d, ok := entity.(std.Describer); ok {
+		return strings.TrimSpace(d.Description())
+	}
+	if v := reflect.ValueOf(entity); v.IsValid() && v.Kind() != reflect.Ptr {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		if d, ok := p.Interface().(std.Describer); ok {
+			return strings.TrimSpace(d.Description())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/log"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// SDL指令及参数名称
+const (
+	DirectiveTable    = "table"
+	DirectiveColumn   = "column"
+	DirectiveRelation = "relation"
+
+	argName        = "name"
+	argPrimary     = "primary"
+	argUnique      = "unique"
+	argType        = "type"
+	argField       = "field"
+	argTargetField = "targetField"
+	argThrough     = "through"
+	argSourceKey   = "sourceKey"
+	argTargetKey   = "targetKey"
+)
+
+// SdlDirectives SDL文件中可用的指令定义，可拼接在schema文件头部供IDE识别
+const SdlDirectives = `
+directive @table(name: String!) on OBJECT
+directive @column(name: String, primary: Boolean, unique: Boolean) on FIELD_DEFINITION
+directive @relation(type: String, field: String, targetField: String, through: String, sourceKey: String, targetKey: String) on FIELD_DEFINITION
+`
+
+// SdlLoader SDL元数据加载器
+// 解析带有@table、@column、@relation指令的GraphQL SDL文件，支持先设计API再映射到已有表
+type SdlLoader struct {
+	cfg *internal.Config
+}
+
+// NewSdlLoader 创建SDL加载器
+func NewSdlLoader(cfg *internal.Config) *SdlLoader {
+	return &SdlLoader{cfg: cfg}
+}
+
+func (my *SdlLoader) Name() string  { return LoaderSdl }
+func (my *SdlLoader) Priority() int { return 90 }
+
+// Support 配置了SDL文件时启用
+func (my *SdlLoader) Support() bool {
+	return my.cfg != nil && my.cfg.Metadata.Sdl != ""
+}
+
+// resolveFilePath 解析SDL文件路径，相对路径基于根目录
+func (my *SdlLoader) resolveFilePath() string {
+	path := modeRegex.ReplaceAllString(my.cfg.Metadata.Sdl, my.cfg.Mode)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(my.cfg.Root, path)
+}
+
+// Load 从SDL文件加载元数据
+// 1. 解析SDL文档(不做schema校验，指令无需预先声明)
+// 2. 对象类型转换为类，带@table的映射到表，否则为虚拟类
+// 3. 标量字段转换为列字段，对象字段转换为虚拟字段
+// 4. 根据@relation补充关系信息
+func (my *SdlLoader) Load(h protocol.Hoster) error {
+	filePath := my.resolveFilePath()
+	log.Info().Str("file", filePath).Msg("开始从SDL文件加载元数据")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取SDL文件失败: %w", err)
+	}
+	doc, err := parser.ParseSchema(&ast.Source{Name: filePath, Input: string(data)})
+	if err != nil {
+		return fmt.Errorf("解析SDL文件失败: %w", err)
+	}
+
+	// 收集对象类型和枚举类型
+	objects := make(map[string]*ast.Definition)
+	enums := make(map[string]bool)
+	for _, def := range doc.Definitions {
+		switch def.Kind {
+		case ast.Object:
+			if isRootType(def.Name) {
+				continue
+			}
+			objects[def.Name] = def
+		case ast.Enum, ast.Scalar:
+			enums[def.Name] = true
+		}
+	}
+
+	// 第一阶段：构建类和字段
+	classes := make(map[string]*protocol.Class, len(objects))
+	for name, def := range objects {
+		class := &protocol.Class{
+			Name:        name,
+			Table:       directiveArg(def.Directives, DirectiveTable, argName),
+			Description: strings.TrimSpace(def.Description),
+			Fields:      make(map[string]*protocol.Field),
+		}
+		class.Virtual = class.Table == ""
+
+		for _, f := range def.Fields {
+			field := &protocol.Field{
+				Name:        f.Name,
+				Type:        f.Type.Name(),
+				IsList:      f.Type.Elem != nil,
+				Nullable:    !f.Type.NonNull,
+				Description: strings.TrimSpace(f.Description),
+			}
+			if _, ok := objects[field.Type]; ok {
+				// 对象字段不对应列，由关系或解析器提供数据
+				field.Virtual = true
+				class.Fields[field.Name] = field
+				continue
+			}
+			if enums[field.Type] {
+				// 自定义枚举和标量按字符串处理
+				field.Type = "String"
+			}
+			field.Column = directiveArg(f.Directives, DirectiveColumn, argName)
+			if field.Column == "" {
+				field.Column = strcase.ToSnake(f.Name)
+			}
+			field.IsPrimary = directiveBool(f.Directives, DirectiveColumn, argPrimary) ||
+				(field.Type == "ID" && f.Name == "id")
+			field.IsUnique = field.IsPrimary || directiveBool(f.Directives, DirectiveColumn, argUnique)
+			if field.IsPrimary {
+				class.PrimaryKeys = append(class.PrimaryKeys, field.Column)
+			}
+			class.Fields[field.Column] = field
+		}
+		classes[name] = class
+	}
+
+	// 第二阶段：处理关系
+	for name, def := range objects {
+		for _, f := range def.Fields {
+			if f.Directives.ForName(DirectiveRelation) == nil {
+				continue
+			}
+			if err := my.applyRelation(classes, classes[name], f); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, class := range classes {
+		key := class.Table
+		if class.Virtual {
+			key = class.Name
+		}
+		_ = h.PutNode(key, class)
+	}
+	log.Info().Int("classes", len(classes)).Msg("从SDL文件加载元数据完成")
+	return nil
+}
+
+// applyRelation 根据@relation指令设置关系
+// 关系统一记录在标量键字段上，与数据库加载器保持一致：
+//   - 多对一: field为本类外键字段，targetField默认为id
+//   - 一对多: 在目标类的外键字段(targetField)上记录反向的多对一关系
+//   - 多对多: 记录在本类键字段上，through指定中间表，sourceKey/targetKey为中间表外键列
+func (my *SdlLoader) applyRelation(classes map[string]*protocol.Class, class *protocol.Class, f *ast.FieldDefinition) error {
+	dirs := f.Directives
+	target, ok := classes[f.Type.Name()]
+	if !ok {
+		return fmt.Errorf("字段%s.%s的关系目标%s不是对象类型", class.Name, f.Name, f.Type.Name())
+	}
+
+	kind := directiveArg(dirs, DirectiveRelation, argType)
+	through := directiveArg(dirs, DirectiveRelation, argThrough)
+	relType := protocol.MANY_TO_ONE
+	switch {
+	case kind != "":
+		relType = relType.Parse(kind)
+	case through != "":
+		relType = protocol.MANY_TO_MANY
+	case f.Type.Elem != nil:
+		relType = protocol.ONE_TO_MANY
+	}
+
+	sourceName := directiveArg(dirs, DirectiveRelation, argField)
+	targetName := directiveArg(dirs, DirectiveRelation, argTargetField)
+
+	switch relType {
+	case protocol.ONE_TO_MANY:
+		if sourceName == "" {
+			sourceName = "id"
+		}
+		if targetName == "" {
+			return fmt.Errorf("一对多关系%s.%s必须指定targetField", class.Name, f.Name)
+		}
+		fk := findField(target, targetName)
+		if fk == nil {
+			return fmt.Errorf("关系字段%s.%s不存在", target.Name, targetName)
+		}
+		if fk.Relation == nil {
+			fk.Relation = &protocol.Relation{
+				Type:        manyToOne(target, class),
+				SourceClass: target.Name,
+				SourceFiled: fk.Name,
+				TargetClass: class.Name,
+				TargetFiled: sourceName,
+			}
+		}
+		return nil
+	default:
+		if sourceName == "" {
+			if relType == protocol.MANY_TO_MANY {
+				sourceName = "id"
+			} else {
+				sourceName = f.Name + "Id"
+			}
+		}
+		if targetName == "" {
+			targetName = "id"
+		}
+		source := findField(class, sourceName)
+		if source == nil {
+			return fmt.Errorf("关系字段%s.%s不存在", class.Name, sourceName)
+		}
+		if relType == protocol.MANY_TO_ONE {
+			relType = manyToOne(class, target)
+		}
+		source.Relation = &protocol.Relation{
+			Type:        relType,
+			SourceClass: class.Name,
+			SourceFiled: source.Name,
+			TargetClass: target.Name,
+			TargetFiled: targetName,
+		}
+		if relType == protocol.MANY_TO_MANY {
+			if through == "" {
+				return fmt.Errorf("多对多关系%s.%s必须指定through", class.Name, f.Name)
+			}
+			source.Relation.Through = &protocol.Through{
+				TableName: through,
+				SourceKey: directiveArg(dirs, DirectiveRelation, argSourceKey),
+				TargetKey: directiveArg(dirs, DirectiveRelation, argTargetKey),
+			}
+		}
+	}
+	return nil
+}
+
+// manyToOne 指向自身的多对一关系视为递归关系
+func manyToOne(source, target *protocol.Class) protocol.RelationType {
+	if source == target {
+		return protocol.RECURSIVE
+	}
+	return protocol.MANY_TO_ONE
+}
+
+// findField 按字段名查找标量字段(字段以列名为键存储)
+func findField(class *protocol.Class, name string) *protocol.Field {
+	for _, field := range class.Fields {
+		if field.Name == name && !field.Virtual {
+			return field
+		}
+	}
+	return nil
+}
+
+// isRootType 判断是否为根操作类型
+func isRootType(name string) bool {
+	return name == "Query" || name == "Mutation" || name == "Subscription"
+}
+
+// directiveArg 读取指令参数的原始字符串值
+func directiveArg(list ast.DirectiveList, directive, arg string) string {
+	if d := list.ForName(directive); d != nil {
+		if a := d.Arguments.ForName(arg); a != nil && a.Value != nil {
+			return a.Value.Raw
+		}
+	}
+	return ""
+}
+
+// directiveBool 读取指令的布尔参数
+func directiveBool(list ast.DirectiveList, directive, arg string) bool {
+	return directiveArg(list, directive, arg) == "true"
+}
@@ -8,6 +8,8 @@ import (
 // Loader名称常量
 const (
 	LoaderFile   = "file"
+	LoaderSdl    = "sdl"
+	LoaderGorm   = "gorm"
 	LoaderPgsql  = "pgsql"
 	LoaderMysql  = "mysql"
 	LoaderConfig = "config"
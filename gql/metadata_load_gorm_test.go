@@ -0,0 +1,113 @@
+package gql
+
+import (
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type gormUser struct {
+	std.Entity
+	Name     string        `gorm:"size:64;not null;unique;comment:用户名"`
+	Articles []gormArticle `gorm:"foreignKey:AuthorId"`
+	Roles    []gormRole    `gorm:"many2many:sys_user_roles"`
+}
+
+func (gormUser) TableName() string   { return "sys_users" }
+func (gormUser) Description() string { return "用户" }
+
+type gormArticle struct {
+	std.Primary
+	Title    string `gorm:"type:varchar(128)"`
+	AuthorId std.Id
+	Author   *gormUser
+	ParentId *std.Id
+	Parent   *gormArticle
+}
+
+func (gormArticle) TableName() string { return "sys_articles" }
+
+type gormRole struct {
+	std.Primary
+	Code string
+}
+
+func (gormRole) TableName() string { return "sys_roles" }
+
+func TestMetadataLoadFromGorm(t *testing.T) {
+	k, err := std.NewKonfig()
+	require.NoError(t, err, "创建配置失败")
+	k.Set("mode", "test")
+	k.Set("app.root", t.TempDir())
+	k.Set("metadata.table-prefix", []string{"sys_"})
+
+	meta, err := NewMetadata(k, nil,
+		WithoutLoader(metadata.LoaderFile),
+		WithEntities(gormUser{}, &gormArticle{}, gormRole{}),
+	)
+	require.NoError(t, err, "从实体创建元数据失败")
+
+	t.Run("类与字段", func(t *testing.T) {
+		user, ok := meta.Nodes["User"]
+		require.True(t, ok, "表名应按命名规范转换为类名")
+		assert.Same(t, user, meta.Nodes["sys_users"])
+		assert.Equal(t, "用户", user.Description)
+		assert.Equal(t, []string{"id"}, user.PrimaryKeys)
+
+		name := user.Fields["name"]
+		require.NotNil(t, name)
+		assert.Equal(t, "varchar", name.Type)
+		assert.Equal(t, "用户名", name.Description)
+		assert.True(t, name.IsUnique)
+		assert.False(t, name.Nullable)
+		assert.True(t, user.Fields["id"].IsPrimary)
+		assert.Equal(t, "bigint", user.Fields["id"].Type)
+		assert.NotNil(t, user.Fields["createdAt"])
+
+		article := meta.Nodes["Article"]
+		require.NotNil(t, article)
+		assert.Equal(t, "varchar", article.Fields["title"].Type)
+		assert.True(t, article.Fields["parentId"].Nullable)
+	})
+
+	t.Run("关系", func(t *testing.T) {
+		article := meta.Nodes["Article"]
+		author := article.Fields["authorId"].Relation
+		require.NotNil(t, author)
+		assert.Equal(t, protocol.MANY_TO_ONE, author.Type)
+		assert.Equal(t, "User", author.TargetClass)
+		assert.Equal(t, "id", author.TargetFiled)
+
+		parent := article.Fields["parentId"].Relation
+		require.NotNil(t, parent)
+		assert.Equal(t, protocol.RECURSIVE, parent.Type)
+
+		roles := meta.Nodes["User"].Fields["id"].Relation
+		require.NotNil(t, roles)
+		assert.Equal(t, protocol.MANY_TO_MANY, roles.Type)
+		assert.Equal(t, "Role", roles.TargetClass)
+		require.NotNil(t, roles.Through)
+		assert.Equal(t, "sys_user_roles", roles.Through.TableName)
+		assert.Equal(t, "gorm_user_id", roles.Through.SourceKey)
+		assert.Equal(t, "gorm_role_id", roles.Through.TargetKey)
+
+		through := meta.Nodes["sys_user_roles"]
+		require.NotNil(t, through, "中间表应自动生成")
+		assert.True(t, through.IsThrough)
+
+		// 关系字段由processRelations统一生成
+		assert.NotNil(t, article.Fields["user"])
+		assert.NotNil(t, meta.Nodes["User"].Fields["articles"])
+		assert.NotNil(t, meta.Nodes["User"].Fields["roles"])
+	})
+
+	t.Run("渲染schema", func(t *testing.T) {
+		executor := newMockExecutor(t, meta)
+		assert.NotNil(t, executor.schema.Types["User"])
+		assert.NotNil(t, executor.schema.Types["Article"].Fields.ForName("user"))
+	})
+}
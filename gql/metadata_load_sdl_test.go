@@ -0,0 +1,150 @@
+package gql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSdl = `
+"用户"
+type User @table(name: "sys_users") {
+  id: ID!
+  "用户名"
+  name: String! @column(name: "user_name", unique: true)
+  status: Status
+  articles: [Article!]! @relation(targetField: "authorId")
+  roles: [Role!]! @relation(through: "sys_user_roles", sourceKey: "user_id", targetKey: "role_id")
+}
+
+type Article @table(name: "sys_articles") {
+  id: ID!
+  title: String
+  authorId: Int
+  parentId: Int
+  author: User @relation(field: "authorId")
+  parent: Article @relation(field: "parentId")
+}
+
+type Role @table(name: "sys_roles") {
+  code: String! @column(primary: true)
+}
+
+type Stat {
+  total: Int
+}
+
+enum Status { ACTIVE DISABLED }
+
+type Query {
+  me: User
+}
+`
+
+func TestMetadataLoadFromSdl(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "cfg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cfg", "schema.graphql"), []byte(testSdl), 0644))
+
+	k, err := std.NewKonfig()
+	require.NoError(t, err, "创建配置失败")
+	k.Set("mode", "test")
+	k.Set("app.root", root)
+	k.Set("metadata.sdl", "cfg/schema.graphql")
+
+	meta, err := NewMetadata(k, nil, WithoutLoader(metadata.LoaderFile))
+	require.NoError(t, err, "从SDL创建元数据失败")
+
+	t.Run("类与表映射", func(t *testing.T) {
+		user, ok := meta.Nodes["User"]
+		require.True(t, ok)
+		assert.Equal(t, "sys_users", user.Table)
+		assert.Equal(t, "用户", user.Description)
+		assert.Same(t, user, meta.Nodes["sys_users"])
+		assert.Equal(t, []string{"id"}, user.PrimaryKeys)
+		assert.NotContains(t, meta.Nodes, "Query")
+
+		stat, ok := meta.Nodes["Stat"]
+		require.True(t, ok)
+		assert.True(t, stat.Virtual)
+	})
+
+	t.Run("字段与列映射", func(t *testing.T) {
+		user := meta.Nodes["User"]
+		name := user.Fields["name"]
+		require.NotNil(t, name)
+		assert.Equal(t, "user_name", name.Column)
+		assert.Equal(t, "用户名", name.Description)
+		assert.True(t, name.IsUnique)
+		assert.False(t, name.Nullable)
+		assert.Same(t, name, user.Fields["user_name"])
+		assert.Equal(t, "String", user.Fields["status"].Type)
+
+		role := meta.Nodes["Role"]
+		assert.Equal(t, []string{"code"}, role.PrimaryKeys)
+		assert.True(t, role.Fields["code"].IsPrimary)
+	})
+
+	t.Run("关系映射", func(t *testing.T) {
+		article := meta.Nodes["Article"]
+		author := article.Fields["authorId"].Relation
+		require.NotNil(t, author)
+		assert.Equal(t, protocol.MANY_TO_ONE, author.Type)
+		assert.Equal(t, "User", author.TargetClass)
+		assert.Equal(t, "id", author.TargetFiled)
+
+		parent := article.Fields["parentId"].Relation
+		require.NotNil(t, parent)
+		assert.Equal(t, protocol.RECURSIVE, parent.Type)
+
+		roles := meta.Nodes["User"].Fields["id"].Relation
+		require.NotNil(t, roles)
+		assert.Equal(t, protocol.MANY_TO_MANY, roles.Type)
+		require.NotNil(t, roles.Through)
+		assert.Equal(t, "sys_user_roles", roles.Through.TableName)
+		assert.Equal(t, "user_id", roles.Through.SourceKey)
+		assert.Equal(t, "role_id", roles.Through.TargetKey)
+
+		// 显式声明的关系字段保留，不再生成默认命名的重复字段
+		assert.True(t, article.Fields["author"].Virtual)
+		assert.NotContains(t, article.Fields, "user")
+		assert.True(t, meta.Nodes["User"].Fields["articles"].Virtual)
+		assert.NotContains(t, meta.Nodes["User"].Fields, "articles1")
+	})
+
+	t.Run("渲染schema", func(t *testing.T) {
+		executor := newMockExecutor(t, meta)
+		assert.NotNil(t, executor.schema.Types["User"].Fields.ForName("articles"))
+		assert.NotNil(t, executor.schema.Types["Article"].Fields.ForName("author"))
+	})
+}
+
+func TestMetadataLoadFromSdlInvalid(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "bad.graphql"), []byte(`
+type Article @table(name: "articles") {
+  id: ID!
+  author: Author @relation(field: "authorId")
+}
+type Author @table(name: "authors") { id: ID! }
+`), 0644))
+
+	k, err := std.NewKonfig()
+	require.NoError(t, err)
+	k.Set("mode", "test")
+	k.Set("app.root", root)
+	k.Set("metadata.sdl", filepath.Join(root, "bad.graphql"))
+
+	cfg := &internal.Config{}
+	require.NoError(t, k.Unmarshal(cfg))
+	loader := metadata.NewSdlLoader(cfg)
+	assert.True(t, loader.Support())
+	assert.Error(t, loader.Load(&Metadata{Nodes: map[string]*protocol.Class{}}), "外键字段不存在时应返回错误")
+}
@@ -0,0 +1,135 @@
+package gql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/samber/lo"
+)
+
+// 生成类型使用的后缀，类名与"类名+后缀"相同会导致schema中类型重名
+var generatedSuffixes = []string{
+	SUFFIX_STATS, SUFFIX_GROUP, SUFFIX_RESULT,
+	SUFFIX_SORT_INPUT, SUFFIX_WHERE_INPUT,
+	SUFFIX_CREATE_INPUT, SUFFIX_UPDATE_INPUT, SUFFIX_UPSERT_INPUT, SUFFIX_INSERT_INPUT,
+}
+
+// 内置标量
+var builtinScalars = []string{
+	SCALAR_ID, SCALAR_INT, SCALAR_FLOAT, SCALAR_STRING, SCALAR_BOOLEAN,
+	SCALAR_JSON, SCALAR_CURSOR, SCALAR_DATE_TIME,
+}
+
+// 渲染器固定生成的类型名称
+var reservedTypes = append([]string{
+	"Query", "Mutation", "Subscription", TYPE_NODE,
+	TYPE_SORT_DIRECTION, TYPE_PAGE_INFO, TYPE_GROUP_BY,
+	TYPE_NUMBER_STATS, TYPE_STRING_STATS, TYPE_DATE_TIME_STATS,
+	ENUM_IS_INPUT, ENUM_SORT_INPUT,
+}, builtinScalars...)
+
+// ValidationError 元数据校验错误，汇总全部问题一次性报告
+type ValidationError struct {
+	Problems []string
+}
+
+func (my *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("元数据校验失败，共%d个问题:", len(my.Problems)))
+	for _, p := range my.Problems {
+		sb.WriteString("\n  - ")
+		sb.WriteString(p)
+	}
+	return sb.String()
+}
+
+// validate 校验最终元数据
+// 汇总加载阶段上报的问题(加载器失败、悬空关系、未知列)以及以下结构问题：
+//   - 驼峰转换后字段名重复
+//   - 非虚拟类缺少主键
+//   - 字段类型没有对应的类型映射
+//   - 类名与生成的类型重名
+func (my *Metadata) validate() error {
+	problems := append([]string{}, my.problems...)
+
+	classes := make([]*protocol.Class, 0, len(my.Nodes))
+	names := make(map[string]bool)
+	for key, class := range my.Nodes {
+		if key == class.Name {
+			classes = append(classes, class)
+			names[class.Name] = true
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+
+	for _, class := range classes {
+		problems = append(problems, my.validateFields(class)...)
+
+		if !class.Virtual && len(class.PrimaryKeys) == 0 {
+			problems = append(problems, fmt.Sprintf("类%s(表%s)缺少主键", class.Name, class.Table))
+		}
+
+		if lo.Contains(reservedTypes, class.Name) {
+			problems = append(problems, fmt.Sprintf("类%s与内置类型重名", class.Name))
+		}
+		for _, suffix := range generatedSuffixes {
+			base, ok := strings.CutSuffix(class.Name, suffix)
+			if !ok || base == "" {
+				continue
+			}
+			if names[base] || lo.Contains(builtinScalars, base) {
+				problems = append(problems, fmt.Sprintf("类%s与生成的类型%s%s重名", class.Name, base, suffix))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateFields 校验类的字段：重复字段名和类型映射缺失
+func (my *Metadata) validateFields(class *protocol.Class) []string {
+	var problems []string
+
+	// 同一字段会以字段名和列名多重索引，按指针去重后再按字段名分组
+	fields := lo.Uniq(lo.Values(class.Fields))
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Name != fields[j].Name {
+			return fields[i].Name < fields[j].Name
+		}
+		return fields[i].Column < fields[j].Column
+	})
+	for name, group := range lo.GroupBy(fields, func(f *protocol.Field) string { return f.Name }) {
+		if len(group) > 1 {
+			columns := lo.Map(group, func(f *protocol.Field, _ int) string { return f.Column })
+			problems = append(problems, fmt.Sprintf("类%s的字段名%s重复，对应列%s", class.Name, name, strings.Join(columns, ",")))
+		}
+	}
+
+	for _, field := range fields {
+		if field.Virtual || field.IsPrimary || field.Type == "" {
+			continue
+		}
+		if !my.isMappedType(field.Type) {
+			problems = append(problems, fmt.Sprintf("字段%s.%s的类型%s没有对应的类型映射", class.Name, field.Name, field.Type))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// isMappedType 判断字段类型能否映射为GraphQL类型，与渲染器的类型解析保持一致
+func (my *Metadata) isMappedType(fieldType string) bool {
+	if lo.Contains(builtinScalars, fieldType) {
+		return true
+	}
+	if _, ok := my.cfg.Schema.TypeMapping[fieldType]; ok {
+		return true
+	}
+	_, ok := my.Nodes[fieldType]
+	return ok
+}
@@ -0,0 +1,99 @@
+package gql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidateKonfig(t *testing.T, mode string) *std.Konfig {
+	k, err := std.NewKonfig()
+	require.NoError(t, err, "创建配置失败")
+	k.Set("mode", mode)
+	k.Set("app.root", t.TempDir())
+	k.Set("metadata.table-prefix", []string{"sys_"})
+	k.Set("metadata.sdl", "missing.graphql")
+	k.Set("metadata.classes", map[string]*internal.ClassConfig{
+		"User": {
+			Table: "users",
+			Fields: map[string]*internal.FieldConfig{
+				"id":        {Column: "id", Type: "int", IsPrimary: true},
+				"user_name": {Column: "user_name", Type: "varchar"},
+				"userName":  {Column: "userName", Type: "varchar"},
+				"location":  {Column: "location", Type: "geometry"},
+				"groupId": {Column: "group_id", Type: "int", Relation: &internal.RelationConfig{
+					TargetClass: "Group", TargetField: "id", Type: "many_to_one",
+				}},
+			},
+		},
+		"Log": {
+			Table:  "logs",
+			Fields: map[string]*internal.FieldConfig{"message": {Column: "message", Type: "text"}},
+		},
+		"UserResult": {
+			Fields: map[string]*internal.FieldConfig{"total": {Type: "Int"}},
+		},
+		"Role": {
+			Table:  "sys_roles",
+			Fields: map[string]*internal.FieldConfig{"title": {Column: "title", Type: "varchar"}},
+		},
+	})
+	return k
+}
+
+func TestMetadataValidate(t *testing.T) {
+	k := newValidateKonfig(t, "test")
+	k.Set("metadata.strict", true)
+
+	_, err := NewMetadata(k, nil, WithoutLoader(metadata.LoaderFile), WithEntities(gormRole{}))
+	require.Error(t, err)
+
+	var report *ValidationError
+	require.True(t, errors.As(err, &report), "应返回汇总的校验错误")
+	problems := report.Problems
+	expects := []string{
+		"加载器sdl执行失败",
+		"关系User.groupId的目标类Group不存在",
+		"类User的字段名userName重复",
+		"类Log(表logs)缺少主键",
+		"字段User.location的类型geometry没有对应的类型映射",
+		"类UserResult与生成的类型UserResult重名",
+		"配置字段Role.title引用了表sys_roles中不存在的列title",
+	}
+	for _, expect := range expects {
+		assert.True(t, containsProblem(problems, expect), "缺少问题: %s\n%s", expect, err)
+	}
+}
+
+func TestMetadataValidateMode(t *testing.T) {
+	// 非生产模式默认不启用严格校验
+	meta, err := NewMetadata(newValidateKonfig(t, "test"), nil, WithoutLoader(metadata.LoaderFile))
+	require.NoError(t, err)
+	assert.False(t, meta.cfg.Metadata.Strict)
+	assert.NotEmpty(t, meta.problems, "问题仍会被收集")
+
+	// 生产模式默认启用
+	_, err = NewMetadata(newValidateKonfig(t, "prod"), nil, WithoutLoader(metadata.LoaderFile))
+	assert.Error(t, err)
+
+	// 显式关闭后生产模式也不校验
+	k := newValidateKonfig(t, "prod")
+	k.Set("metadata.strict", false)
+	_, err = NewMetadata(k, nil, WithoutLoader(metadata.LoaderFile))
+	assert.NoError(t, err)
+}
+
+func containsProblem(problems []string, expect string) bool {
+	for _, p := range problems {
+		if strings.HasPrefix(p, expect) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package gql
+
+import (
+	"slices"
+	"sync"
+)
+
+// OTHER_OPERATION 超出标签上限的具名操作在指标中使用的名称
+const OTHER_OPERATION = "other"
+
+// operationLabels 操作指标中已使用的名称标签，所有快照共享
+type operationLabels struct {
+	mu   sync.RWMutex
+	seen map[string]bool
+}
+
+// admit 返回操作名对应的标签，已使用的名称保持不变，新名称在数量达到上限前加入，之后统计为other
+func (my *operationLabels) admit(name string, max int) string {
+	my.mu.RLock()
+	ok, full := my.seen[name], len(my.seen) >= max
+	my.mu.RUnlock()
+	if ok {
+		return name
+	}
+	if full {
+		return OTHER_OPERATION
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if !my.seen[name] && len(my.seen) >= max {
+		return OTHER_OPERATION
+	}
+	my.seen[name] = true
+	return name
+}
+
+// operationLabel 操作指标的名称标签，客户端可以任意命名操作，标签取值需要限制
+// 可信文档清单和配置中列出的操作名始终作为标签，其他操作名按出现顺序使用，达到上限后统计为other；匿名操作为空
+func (my *Executor) operationLabel(query, name string) string {
+	if name == "" || my.trusted.contains(query) {
+		return name
+	}
+	cfg := my.metadata.cfg.Executor.Metrics
+	if slices.Contains(cfg.Operations, name) {
+		return name
+	}
+	return my.labels.admit(name, cfg.MaxOperations)
+}
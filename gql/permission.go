@@ -0,0 +1,201 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/internal/intro"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"github.com/ichaly/ideabase/utl"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// CODE_FORBIDDEN 当前角色无权执行操作
+const CODE_FORBIDDEN = "FORBIDDEN"
+
+// roleSchema 角色专属的schema和自省处理器
+type roleSchema struct {
+	schema *ast.Schema
+	intro  *intro.Handler
+	vars   []string // 行过滤模板引用的保留变量
+}
+
+// restricted 判断是否有类配置了权限规则
+func (my *Metadata) restricted() bool {
+	for _, class := range my.Nodes {
+		if len(class.Permissions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// roles 返回权限规则中出现的所有角色，以及默认角色和匿名角色
+func (my *Metadata) roles() []string {
+	set := map[string]bool{}
+	for _, class := range my.Nodes {
+		for role := range class.Permissions {
+			set[role] = true
+		}
+	}
+	for _, role := range []string{my.cfg.Metadata.Roles.Default, my.cfg.Metadata.Roles.Anonymous} {
+		if role != "" {
+			set[role] = true
+		}
+	}
+	return utl.SortKeys(set)
+}
+
+// allow 判断视图的角色能否在类上执行操作，完整元数据不限制
+func (my *Metadata) allow(class *protocol.Class, op string) bool {
+	if my.role == "" {
+		return true
+	}
+	p, ok := class.Permission(my.role)
+	return ok && p.Allow(op)
+}
+
+// view 返回角色可见的元数据视图，用于渲染角色专属的schema
+// 视图只包含角色有权访问的类和字段，类型为不可见类的关系字段一并移除
+func (my *Metadata) view(role string) *Metadata {
+	visible := func(class *protocol.Class) (*protocol.Permission, bool) {
+		p, ok := class.Permission(role)
+		return p, ok && (p == nil || len(p.Operations) > 0)
+	}
+
+	view := *my
+	view.role = role
+	view.Nodes = make(map[string]*protocol.Class, len(my.Nodes))
+	// 类名和表名索引指向同一个类，复制后保持共享
+	clones := make(map[*protocol.Class]*protocol.Class)
+	for key, class := range my.Nodes {
+		p, ok := visible(class)
+		if !ok {
+			continue
+		}
+		clone, ok := clones[class]
+		if !ok {
+			c := *class
+			c.Fields = make(map[string]*protocol.Field, len(class.Fields))
+			for name, field := range class.Fields {
+				if !p.Visible(field) {
+					continue
+				}
+				if target, ok := my.Nodes[strings.Trim(field.Type, "[]")]; ok {
+					if _, ok := visible(target); !ok {
+						continue
+					}
+				}
+				c.Fields[name] = field
+			}
+			clone = &c
+			clones[class] = clone
+		}
+		view.Nodes[key] = clone
+	}
+	return &view
+}
+
+// templateVariables 返回角色的行过滤模板引用的保留变量
+func (my *Metadata) templateVariables(role string) []string {
+	set := map[string]bool{}
+	for _, class := range my.Nodes {
+		if p, ok := class.Permission(role); ok && p != nil {
+			compiler.TemplateVariables(p.Filter, set)
+		}
+	}
+	return utl.SortKeys(set)
+}
+
+// queryable 判断视图中是否有可查询的类，GraphQL要求查询根类型至少包含一个字段
+func (my *Metadata) queryable() bool {
+	for key, class := range my.Nodes {
+		if key == class.Name && my.allow(class, protocol.OP_QUERY) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadRoleSchemas 为每个角色渲染并加载专属schema，未配置权限规则时返回空
+// 没有任何可查询类的角色不生成schema，其请求一律拒绝
+func loadRoleSchemas(meta *Metadata) (map[string]*roleSchema, error) {
+	if !meta.restricted() {
+		return nil, nil
+	}
+	schemas := make(map[string]*roleSchema)
+	for _, role := range meta.roles() {
+		view := meta.view(role)
+		if !view.queryable() {
+			continue
+		}
+		s, err := loadSchema(NewRenderer(view))
+		if err != nil {
+			return nil, fmt.Errorf("生成角色%s的schema失败: %w", role, err)
+		}
+		schemas[role] = &roleSchema{schema: s, intro: intro.New(s), vars: meta.templateVariables(role)}
+	}
+	return schemas, nil
+}
+
+// authorize 识别请求角色，并将角色、当前用户和JWT声明写入保留变量供权限规则引用
+// 角色优先取JWT声明，已登录但未声明角色时使用默认角色，未登录时使用匿名角色
+// 以$开头的保留变量只由服务端写入，客户端提交的同名变量一律丢弃；模板引用的声明缺失时绑定为null，过滤条件不匹配任何行
+// 未配置权限规则时角色为空，变量原样返回
+func (my *Executor) authorize(ctx context.Context, variables map[string]interface{}) (string, map[string]interface{}) {
+	if my.roles == nil {
+		return "", variables
+	}
+	cfg := my.metadata.cfg.Metadata.Roles
+	claims := std.GetAuthClaims(ctx)
+	user := std.GetAuditUser(ctx)
+
+	role := cfg.Anonymous
+	if name, ok := claims[cfg.Claim].(string); ok && name != "" {
+		role = name
+	} else if user > 0 || len(claims) > 0 {
+		role = cfg.Default
+	}
+
+	vars := make(map[string]interface{}, len(variables)+len(claims)+2)
+	for k, v := range variables {
+		if !strings.HasPrefix(k, "$") {
+			vars[k] = v
+		}
+	}
+	if s, ok := my.roles[role]; ok {
+		for _, name := range s.vars {
+			vars[name] = nil
+		}
+	}
+	for k, v := range claims {
+		vars[compiler.VAR_CLAIMS+k] = v
+	}
+	vars[compiler.VAR_ROLE] = role
+	vars[compiler.VAR_USER] = nil
+	if user > 0 {
+		vars[compiler.VAR_USER] = uint64(user)
+	}
+	return role, vars
+}
+
+// schemaFor 返回角色使用的schema和自省处理器，角色没有可用schema时返回false
+func (my *Executor) schemaFor(role string) (*ast.Schema, *intro.Handler, bool) {
+	if my.roles == nil {
+		return my.schema, my.intro, true
+	}
+	s, ok := my.roles[role]
+	if !ok {
+		return nil, nil, false
+	}
+	return s.schema, s.intro, true
+}
+
+// forbidden 判断错误是否由权限规则拒绝
+func forbidden(err error) bool {
+	return errors.Is(err, compiler.ErrForbidden)
+}
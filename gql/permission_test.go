@@ -0,0 +1,197 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// newPermissionExecutor 创建配置了权限规则的测试执行器
+// user角色只能查询和创建自己的文章且看不到用户邮箱，writer角色只能访问声明uid对应的文章，admin角色不受限制，匿名请求没有可用的schema
+func newPermissionExecutor(t *testing.T) (*Executor, *countingDialect) {
+	meta := createMockMetadata(t)
+	meta.cfg.Metadata.Roles = internal.RoleConfig{Claim: "role", Default: "user", Anonymous: "anonymous"}
+	all := []string{protocol.OP_QUERY, protocol.OP_CREATE, protocol.OP_UPDATE, protocol.OP_DELETE}
+	meta.Nodes["User"].Permissions = map[string]*protocol.Permission{
+		"user":  {Operations: []string{protocol.OP_QUERY}, ExcludeFields: []string{"email"}},
+		"admin": {Operations: all},
+	}
+	meta.Nodes["Post"].Permissions = map[string]*protocol.Permission{
+		"user": {
+			Operations: []string{protocol.OP_QUERY, protocol.OP_CREATE},
+			Filter:     map[string]interface{}{"userId": map[string]interface{}{"eq": "$user"}},
+		},
+		"writer": {
+			Operations: []string{protocol.OP_QUERY},
+			Filter:     map[string]interface{}{"userId": map[string]interface{}{"eq": "$claims.uid"}},
+		},
+		"admin": {Operations: all},
+	}
+	executor := newMockExecutor(t, meta)
+	dialect := &countingDialect{}
+	executor.compiler = &Compiler{meta: meta, dialect: dialect}
+	executor.plans = newPlanCache(10)
+	return executor, dialect
+}
+
+func TestPermissionSchema(t *testing.T) {
+	executor, _ := newPermissionExecutor(t)
+	require.Contains(t, executor.roles, "user")
+	require.Contains(t, executor.roles, "admin")
+	assert.NotContains(t, executor.roles, "anonymous", "没有可查询类的角色不生成schema")
+
+	user := executor.roles["user"].schema
+	assert.Nil(t, user.Types["User"].Fields.ForName("email"), "禁止访问的字段不出现在角色schema中")
+	assert.NotNil(t, user.Mutation.Fields.ForName("createPost"))
+	assert.Nil(t, user.Mutation.Fields.ForName("updatePost"))
+	assert.Nil(t, user.Mutation.Fields.ForName("createUser"))
+
+	admin := executor.roles["admin"].schema
+	assert.NotNil(t, admin.Types["User"].Fields.ForName("email"))
+	assert.NotNil(t, admin.Mutation.Fields.ForName("deleteUser"))
+
+	writer := executor.roles["writer"].schema
+	assert.Nil(t, writer.Types["User"], "不可见的类不生成类型")
+	assert.Nil(t, writer.Mutation, "没有变更权限时不生成变更根类型")
+	assert.NotNil(t, executor.schema.Types["User"].Fields.ForName("email"), "完整schema不受影响")
+}
+
+func TestPermissionExecute(t *testing.T) {
+	executor, dialect := newPermissionExecutor(t)
+	withUser := func(id std.Id, claims jwt.MapClaims) context.Context {
+		return std.SetAuthClaims(std.SetAuditUser(context.Background(), id), claims)
+	}
+
+	t.Run("匿名请求没有可用的schema", func(t *testing.T) {
+		r := executor.Execute(context.Background(), `{ users { total } }`, nil, "")
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, CODE_FORBIDDEN, r.Errors[0].Extensions["code"])
+	})
+
+	t.Run("按角色schema校验字段", func(t *testing.T) {
+		query := `{ users { items { email } } }`
+		r := executor.Execute(withUser(7, nil), query, nil, "")
+		require.NotEmpty(t, r.Errors)
+		assert.Equal(t, CODE_GRAPHQL_VALIDATION_FAILED, r.Errors[0].Extensions["code"])
+
+		role, _ := executor.authorize(withUser(7, jwt.MapClaims{"role": "admin"}), nil)
+		assert.Equal(t, "admin", role, "角色优先取JWT声明")
+		_, err := executor.prepare(context.Background(), query, "", role)
+		assert.NoError(t, err)
+	})
+
+	t.Run("行过滤条件按当前用户绑定", func(t *testing.T) {
+		build := func(ctx context.Context) (string, []any) {
+			role, vars := executor.authorize(ctx, nil)
+			entry, err := executor.prepare(context.Background(), `{ posts { total } }`, "", role)
+			require.NoError(t, err)
+			sql, args, err := executor.build(entry, entry.data, vars)
+			require.NoError(t, err)
+			return sql, args
+		}
+		dialect.builds = 0
+		sql, args := build(withUser(7, nil))
+		assert.Equal(t, `SELECT "posts" AND userId = $1`, sql)
+		assert.Equal(t, []any{uint64(7)}, args)
+
+		_, args = build(withUser(8, nil))
+		assert.Equal(t, []any{uint64(8)}, args, "缓存的计划按请求重新绑定当前用户")
+		assert.Equal(t, 1, dialect.builds)
+
+		sql, args = build(withUser(8, jwt.MapClaims{"role": "admin"}))
+		assert.Equal(t, `SELECT "posts"`, sql, "不同角色使用独立的计划")
+		assert.Empty(t, args)
+	})
+
+	t.Run("客户端不能伪造保留变量", func(t *testing.T) {
+		build := func(ctx context.Context, variables map[string]interface{}) (string, []any) {
+			role, vars := executor.authorize(ctx, variables)
+			entry, err := executor.prepare(context.Background(), `{ posts { total } }`, "", role)
+			require.NoError(t, err)
+			sql, args, err := executor.build(entry, entry.data, vars)
+			require.NoError(t, err)
+			return sql, args
+		}
+		forged := map[string]interface{}{"$claims.uid": 99, "$user": 99, "$role": "admin"}
+
+		sql, args := build(withUser(7, jwt.MapClaims{"role": "writer"}), forged)
+		assert.Equal(t, `SELECT "posts" AND userId = $1`, sql)
+		assert.Equal(t, []any{nil}, args, "声明缺失时绑定为null，不使用客户端变量")
+
+		_, args = build(withUser(7, jwt.MapClaims{"role": "writer", "uid": "5"}), forged)
+		assert.Equal(t, []any{"5"}, args)
+
+		_, args = build(withUser(7, nil), forged)
+		assert.Equal(t, []any{uint64(7)}, args)
+	})
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	executor, _ := newPermissionExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(context.Context, string) (string, error) {
+		return `{"users":{"items":[{"email":"a@b.c"}]}}`, nil
+	})
+	var claims jwt.MapClaims
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		if err := std.AuthVerified(c, claims); err != nil {
+			return err
+		}
+		return c.Next()
+	})
+	app.Post("/graphql", executor.Handler)
+	execute := func() gqlReply {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ users { items { email } } }"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var r gqlReply
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&r))
+		return r
+	}
+
+	claims = jwt.MapClaims{"sub": "7"}
+	r := execute()
+	require.NotEmpty(t, r.Errors)
+	assert.Equal(t, CODE_GRAPHQL_VALIDATION_FAILED, r.Errors[0].Extensions["code"], "没有角色声明时使用默认角色")
+
+	claims = jwt.MapClaims{"sub": "7", "role": "admin"}
+	r = execute()
+	require.Empty(t, r.Errors, "中间件写入的角色声明生效")
+	assert.NotNil(t, r.Data["users"])
+}
+
+func TestPermissionSubscribe(t *testing.T) {
+	executor, _ := newPermissionExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		return `{"users":{"total":1}}`, nil
+	})
+	user := std.SetAuditUser(context.Background(), 7)
+
+	_, err := executor.Subscribe(context.Background(), `query @live { users { total } }`, nil, "")
+	var errs gqlerror.List
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, CODE_FORBIDDEN, errs[0].Extensions["code"], "匿名请求没有可用的schema")
+
+	_, err = executor.Subscribe(user, `query @live { users { items { email } } }`, nil, "")
+	assert.ErrorContains(t, err, "email", "按角色schema校验订阅的字段")
+
+	ctx, cancel := context.WithCancel(user)
+	defer cancel()
+	out, err := executor.Subscribe(ctx, `query @live { users { total } }`, nil, "")
+	require.NoError(t, err)
+	r := <-out
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, map[string]interface{}{"users": map[string]interface{}{"total": float64(1)}}, r.Data)
+}
@@ -0,0 +1,150 @@
+package gql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// 自动持久化查询（APQ）相关常量
+// 参考: https://github.com/apollographql/apollo-link-persisted-queries
+const (
+	persistedVersion   = 1
+	persistedKeyPrefix = "gql:apq:"
+	persistedRecent    = 1000 // 进程内保留的最近注册的查询数
+
+	CODE_PERSISTED_QUERY_NOT_FOUND     = "PERSISTED_QUERY_NOT_FOUND"
+	CODE_PERSISTED_QUERY_NOT_SUPPORTED = "PERSISTED_QUERY_NOT_SUPPORTED"
+	CODE_PERSISTED_QUERY_HASH_MISMATCH = "PERSISTED_QUERY_HASH_MISMATCH"
+)
+
+type (
+	// gqlExtensions 请求扩展字段
+	gqlExtensions struct {
+		PersistedQuery *persistedQuery `json:"persistedQuery,omitempty"`
+	}
+
+	// persistedQuery APQ扩展，客户端先只发送哈希，未命中时再携带完整查询注册
+	persistedQuery struct {
+		Version    int    `json:"version"`
+		Sha256Hash string `json:"sha256Hash"`
+	}
+)
+
+// resolvePersisted 解析自动持久化查询，返回实际要执行的查询文本
+// 只有哈希时从缓存读取查询；同时携带查询时校验哈希并写入缓存
+func (my *Executor) resolvePersisted(ctx context.Context, req gqlQuery) (string, *gqlerror.Error) {
+	pq := req.Extensions.PersistedQuery
+	if pq == nil {
+		return req.Query, nil
+	}
+	if my.cache == nil || pq.Version != persistedVersion {
+		return "", newCodeError(CODE_PERSISTED_QUERY_NOT_SUPPORTED, "PersistedQueryNotSupported")
+	}
+
+	key := persistedKeyPrefix + pq.Sha256Hash
+	if req.Query == "" {
+		if query, ok := my.persisted.get(pq.Sha256Hash); ok {
+			return query, nil
+		}
+		data, err := my.cache.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, cache.ErrNotFound) {
+				log.Warn().Err(err).Msg("读取持久化查询失败")
+			}
+			return "", newCodeError(CODE_PERSISTED_QUERY_NOT_FOUND, "PersistedQueryNotFound")
+		}
+		return string(data), nil
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	if hex.EncodeToString(sum[:]) != pq.Sha256Hash {
+		return "", newCodeError(CODE_PERSISTED_QUERY_HASH_MISMATCH, "provided sha does not match query")
+	}
+	ttl := my.current.Load().metadata.cfg.Executor.Persisted.Ttl
+	my.persisted.put(pq.Sha256Hash, req.Query, ttl)
+	if err := my.cache.Set(ctx, key, []byte(req.Query), ttl); err != nil {
+		log.Warn().Err(err).Msg("保存持久化查询失败")
+	}
+	return req.Query, nil
+}
+
+// persistedEntry 进程内保存的持久化查询
+type persistedEntry struct {
+	hash    string
+	query   string
+	expires time.Time // 过期时间，零值表示不过期
+}
+
+// persistedQueries 最近注册的持久化查询，按最近最少使用淘汰，所有快照共享
+// 缓存可能异步写入（如内存缓存），注册后立即按哈希查询时缓存未必可读，先从这里读取
+type persistedQueries struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+// newPersistedQueries 创建进程内的持久化查询存储
+func newPersistedQueries(capacity int) *persistedQueries {
+	return &persistedQueries{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get 按哈希读取未过期的查询
+func (my *persistedQueries) get(hash string) (string, bool) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	e, ok := my.items[hash]
+	if !ok {
+		return "", false
+	}
+	entry := e.Value.(*persistedEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		my.order.Remove(e)
+		delete(my.items, hash)
+		return "", false
+	}
+	my.order.MoveToFront(e)
+	return entry.query, true
+}
+
+// put 保存查询，超出容量时淘汰最久未使用的查询
+func (my *persistedQueries) put(hash, query string, ttl time.Duration) {
+	entry := &persistedEntry{hash: hash, query: query}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if e, ok := my.items[hash]; ok {
+		e.Value = entry
+		my.order.MoveToFront(e)
+		return
+	}
+	my.items[hash] = my.order.PushFront(entry)
+	for my.order.Len() > my.capacity {
+		last := my.order.Back()
+		my.order.Remove(last)
+		delete(my.items, last.Value.(*persistedEntry).hash)
+	}
+}
+
+// newCodeError 创建带extensions.code的GraphQL错误
+func newCodeError(code, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
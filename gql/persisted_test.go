@@ -0,0 +1,146 @@
+package gql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/cache"
+	_ "github.com/ichaly/ideabase/std/cache/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pendingCache 写入尚未生效的缓存，模拟异步写入的缓存在注册后立即读取的情况
+type pendingCache struct{ cache.Cache }
+
+func (my pendingCache) Get(context.Context, string) ([]byte, error) { return nil, cache.ErrNotFound }
+func (my pendingCache) Set(context.Context, string, []byte, time.Duration, ...string) error {
+	return nil
+}
+
+func TestPersistedQuery(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Executor.Persisted.Ttl = time.Minute
+	meta.cfg.Executor.CacheControl.MaxAge = time.Minute
+	meta.cfg.Root = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(meta.cfg.Root, "cfg"), 0755))
+	store, err := cache.New(nil)
+	require.NoError(t, err)
+	executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil, WithCache(store))
+	require.NoError(t, err)
+
+	app := fiber.New()
+	executor.Bind(app.Group(executor.Path()))
+
+	query := `{ __schema { queryType { name } } }`
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + hash + `"}}`
+
+	get := func(params url.Values) (*http.Response, string) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, executor.Path()+"?"+params.Encode(), nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("未注册的哈希", func(t *testing.T) {
+		_, body := get(url.Values{"extensions": {extensions}})
+		assert.Contains(t, body, CODE_PERSISTED_QUERY_NOT_FOUND)
+	})
+
+	t.Run("哈希不匹配", func(t *testing.T) {
+		_, body := get(url.Values{"query": {"{ __schema { types { name } } }"}, "extensions": {extensions}})
+		assert.Contains(t, body, CODE_PERSISTED_QUERY_HASH_MISMATCH)
+	})
+
+	t.Run("注册后按哈希查询", func(t *testing.T) {
+		resp, body := get(url.Values{"query": {query}, "extensions": {extensions}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, `"queryType":{"name":"Query"}`)
+		assert.Equal(t, "public, max-age=60", resp.Header.Get(fiber.HeaderCacheControl))
+
+		resp, body = get(url.Values{"extensions": {extensions}})
+		assert.Contains(t, body, `"queryType":{"name":"Query"}`)
+		assert.Equal(t, "public, max-age=60", resp.Header.Get(fiber.HeaderCacheControl))
+	})
+
+	t.Run("GET禁止变更", func(t *testing.T) {
+		resp, _ := get(url.Values{"query": {`mutation { deleteUser(id: 1) }`}})
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		assert.Equal(t, fiber.MethodPost, resp.Header.Get(fiber.HeaderAllow))
+	})
+
+	t.Run("无效的变量", func(t *testing.T) {
+		resp, _ := get(url.Values{"query": {query}, "variables": {"{"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("失败的查询不缓存", func(t *testing.T) {
+		resp, _ := get(url.Values{"query": {"{ unknown }"}})
+		assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+	})
+
+	t.Run("POST请求不缓存", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, executor.Path(), strings.NewReader(`{"extensions":`+extensions+`}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `"queryType":{"name":"Query"}`)
+		assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+	})
+
+	t.Run("缓存写入未生效时注册后立即可读", func(t *testing.T) {
+		executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil, WithCache(pendingCache{}))
+		require.NoError(t, err)
+		app := fiber.New()
+		executor.Bind(app.Group(executor.Path()))
+		for _, params := range []url.Values{{"query": {query}, "extensions": {extensions}}, {"extensions": {extensions}}} {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, executor.Path()+"?"+params.Encode(), nil))
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Contains(t, string(body), `"queryType":{"name":"Query"}`)
+		}
+	})
+
+	t.Run("未配置缓存", func(t *testing.T) {
+		executor := newMockExecutor(t, createMockMetadata(t))
+		app := fiber.New()
+		executor.Bind(app.Group(executor.Path()))
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, executor.Path()+"?"+url.Values{"extensions": {extensions}}.Encode(), nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(body), CODE_PERSISTED_QUERY_NOT_SUPPORTED)
+	})
+}
+
+func TestPersistedQueries(t *testing.T) {
+	queries := newPersistedQueries(2)
+	queries.put("a", "query a", 0)
+	queries.put("b", "query b", 0)
+	_, ok := queries.get("a")
+	require.True(t, ok)
+	queries.put("c", "query c", 0)
+	_, ok = queries.get("b")
+	assert.False(t, ok, "超出容量时淘汰最久未使用的查询")
+	query, ok := queries.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "query a", query)
+
+	queries.put("d", "query d", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, ok = queries.get("d")
+	assert.False(t, ok, "过期的查询不再返回")
+}
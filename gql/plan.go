@@ -0,0 +1,154 @@
+package gql
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/lexer"
+)
+
+// plan 编译后的SQL模板，变量通过参数槽重新绑定
+type plan struct {
+	sql       string
+	args      []any
+	slots     []compiler.Slot
+	cacheable bool
+}
+
+// bind 使用新的变量填充参数槽，返回本次执行的参数列表
+func (my *plan) bind(variables map[string]interface{}) ([]any, error) {
+	if len(my.slots) == 0 {
+		return my.args, nil
+	}
+	args := append([]any(nil), my.args...)
+	for _, slot := range my.slots {
+		val, err := slot.Value.Value(variables)
+		if err != nil {
+			return nil, err
+		}
+		args[slot.Index] = val
+	}
+	return args, nil
+}
+
+// planEntry 缓存的操作，包含校验后的AST和首次编译得到的SQL模板
+type planEntry struct {
+	key       string
+	operation *ast.OperationDefinition // 校验后的完整操作
+	intro     []*ast.Field             // 根选择集中的自省字段
+	data      *ast.OperationDefinition // 交给编译器的数据字段，没有数据字段时为空
+	plan      atomic.Pointer[plan]
+}
+
+// PlanStats 查询计划缓存统计
+type PlanStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+// planCache 按最近最少使用淘汰的查询计划缓存
+// 每个schema快照使用独立的缓存，热加载后旧计划随旧快照失效，命中统计在快照之间累计
+type planCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	hits     *atomic.Uint64
+	misses   *atomic.Uint64
+}
+
+// newPlanCache 创建查询计划缓存，capacity不大于0时不缓存
+func newPlanCache(capacity int) *planCache {
+	return &planCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		hits:     &atomic.Uint64{},
+		misses:   &atomic.Uint64{},
+	}
+}
+
+// fork 创建空的缓存并沿用命中统计
+func (my *planCache) fork(capacity int) *planCache {
+	next := newPlanCache(capacity)
+	next.hits, next.misses = my.hits, my.misses
+	return next
+}
+
+// get 查找缓存的操作并更新命中统计
+func (my *planCache) get(key string) (*planEntry, bool) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	e, ok := my.items[key]
+	if !ok {
+		my.misses.Add(1)
+		return nil, false
+	}
+	my.hits.Add(1)
+	my.order.MoveToFront(e)
+	return e.Value.(*planEntry), true
+}
+
+// add 缓存操作，超出容量时淘汰最久未使用的操作
+func (my *planCache) add(entry *planEntry) {
+	if my.capacity <= 0 {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if e, ok := my.items[entry.key]; ok {
+		my.order.MoveToFront(e)
+		return
+	}
+	my.items[entry.key] = my.order.PushFront(entry)
+	for my.order.Len() > my.capacity {
+		last := my.order.Back()
+		my.order.Remove(last)
+		delete(my.items, last.Value.(*planEntry).key)
+	}
+}
+
+// stats 返回缓存统计
+func (my *planCache) stats() PlanStats {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	return PlanStats{Hits: my.hits.Load(), Misses: my.misses.Load(), Size: my.order.Len()}
+}
+
+// planKey 计算规范化文档摘要、操作名和角色组成的缓存键
+// 规范化忽略空白、逗号和注释，格式不同但内容相同的文档共用同一个计划
+// 不同角色的schema和权限规则不同，编译结果不能共用
+func planKey(query, operationName, role string) string {
+	h := sha256.New()
+	l := lexer.New(&ast.Source{Input: query})
+	for {
+		tok, err := l.ReadToken()
+		if err != nil {
+			// 无法解析的文档按原文计算，解析失败的结果不会被缓存
+			h.Reset()
+			h.Write([]byte(query))
+			break
+		}
+		if tok.Kind == lexer.EOF {
+			break
+		}
+		if tok.Kind == lexer.Comment {
+			continue
+		}
+		h.Write([]byte(strconv.Itoa(int(tok.Kind))))
+		h.Write([]byte(strconv.Quote(tok.Value)))
+	}
+	return hex.EncodeToString(h.Sum(nil)) + ":" + operationName + ":" + role
+}
+
+// PlanStats 返回查询计划缓存的命中统计
+func (my *Executor) PlanStats() PlanStats {
+	return my.current.Load().plans.stats()
+}
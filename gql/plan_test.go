@@ -0,0 +1,161 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// countingDialect 记录编译次数的测试方言，id参数作为参数槽，limit参数决定SQL结构，offset参数产生编译错误
+// 权限规则中的行过滤条件以等值条件追加，name用于模拟其他方言的执行行为
+type countingDialect struct {
+	name   string
+	builds int
+}
+
+func (my *countingDialect) Name() string {
+	if my.name != "" {
+		return my.name
+	}
+	return "counting"
+}
+func (my *countingDialect) Quotation() string            { return `"` }
+func (my *countingDialect) Placeholder(index int) string { return fmt.Sprintf("$%d", index) }
+func (my *countingDialect) BuildMutation(ctx *compiler.Context, set ast.SelectionSet) error {
+	return my.BuildQuery(ctx, set)
+}
+func (my *countingDialect) BuildQuery(ctx *compiler.Context, set ast.SelectionSet) error {
+	my.builds++
+	ctx.Write("SELECT")
+	for _, s := range set {
+		field := s.(*ast.Field)
+		ctx.SpaceBefore().Quote(field.Name)
+		if arg := field.Arguments.ForName(ID); arg != nil {
+			index, err := ctx.Param(arg.Value)
+			if err != nil {
+				return err
+			}
+			ctx.Space("WHERE id =").Write(my.Placeholder(index))
+		}
+		if arg := field.Arguments.ForName(OFFSET); arg != nil {
+			return compiler.Errorf(arg.Position, "不支持offset参数")
+		}
+		if arg := field.Arguments.ForName(LIMIT); arg != nil {
+			limit, err := ctx.Value(arg.Value)
+			if err != nil {
+				return err
+			}
+			ctx.Space("LIMIT").Write(limit)
+		}
+		class := strings.TrimSuffix(field.Definition.Type.Name(), SUFFIX_RESULT)
+		if err := ctx.Allow(field.Position, class, protocol.OP_QUERY); err != nil {
+			return err
+		}
+		filter, err := ctx.Filter(class)
+		if err != nil {
+			return err
+		}
+		if filter != nil {
+			for _, c := range filter.Children {
+				index, err := ctx.Param(c.Value.Children[0].Value)
+				if err != nil {
+					return err
+				}
+				ctx.Space("AND").Write(c.Name, " = ", my.Placeholder(index))
+			}
+		}
+	}
+	return nil
+}
+
+func TestPlanKey(t *testing.T) {
+	key := planKey(`query Q { users { total } }`, "Q", "")
+	assert.Equal(t, key, planKey("# 注释\nquery Q {\n  users,\n  { total }\n}", "Q", ""), "忽略空白、逗号和注释")
+	assert.NotEqual(t, key, planKey(`query Q { users { total } }`, "", ""), "操作名不同")
+	assert.NotEqual(t, key, planKey(`query Q { posts { total } }`, "Q", ""))
+	assert.NotEqual(t, planKey(`{ users(where: {name: {eq: "a b"}}) { total } }`, "", ""),
+		planKey(`{ users(where: {name: {eq: "ab"}}) { total } }`, "", ""), "字符串内的空白有意义")
+	assert.NotEqual(t, key, planKey(`query Q { users { total } }`, "Q", "user"), "角色不同")
+}
+
+func TestPlanCache(t *testing.T) {
+	c := newPlanCache(2)
+	for _, key := range []string{"a", "b"} {
+		c.add(&planEntry{key: key})
+	}
+	_, ok := c.get("a")
+	require.True(t, ok)
+	c.add(&planEntry{key: "c"})
+
+	_, ok = c.get("b")
+	assert.False(t, ok, "淘汰最久未使用的计划")
+	_, ok = c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, PlanStats{Hits: 2, Misses: 1, Size: 2}, c.stats())
+
+	// 热加载后清空计划，统计继续累计
+	next := c.fork(2)
+	_, ok = next.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, PlanStats{Hits: 2, Misses: 2}, next.stats())
+
+	// 容量为0时不缓存
+	disabled := newPlanCache(0)
+	disabled.add(&planEntry{key: "a"})
+	_, ok = disabled.get("a")
+	assert.False(t, ok)
+}
+
+func TestPlanBind(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Executor.Plan.Size = 10
+	dialect := &countingDialect{}
+	executor := newMockExecutor(t, meta)
+	executor.compiler = &Compiler{meta: meta, dialect: dialect}
+	executor.plans = newPlanCache(10)
+
+	build := func(query string, vars map[string]interface{}) (string, []any) {
+		entry, err := executor.prepare(context.Background(), query, "", "")
+		require.NoError(t, err)
+		sql, args, err := executor.build(entry, entry.operation, vars)
+		require.NoError(t, err)
+		return sql, args
+	}
+
+	t.Run("变量绑定到缓存的参数槽", func(t *testing.T) {
+		query := `query($id: ID = 7) { users(id: $id) { total } }`
+		sql, args := build(query, map[string]interface{}{"id": 1})
+		assert.Equal(t, `SELECT "users" WHERE id = $1`, sql)
+		assert.Equal(t, []any{1}, args)
+
+		sql, args = build(query, map[string]interface{}{"id": 2})
+		assert.Equal(t, `SELECT "users" WHERE id = $1`, sql)
+		assert.Equal(t, []any{2}, args)
+
+		_, args = build(query, nil)
+		assert.Equal(t, []any{int64(7)}, args, "未提供变量时使用默认值")
+		assert.Equal(t, 1, dialect.builds, "命中缓存时不再编译")
+	})
+
+	t.Run("变量决定SQL结构时不缓存", func(t *testing.T) {
+		dialect.builds = 0
+		query := `query($n: Int) { users(limit: $n) { total } }`
+		sql, _ := build(query, map[string]interface{}{"n": 5})
+		assert.Equal(t, `SELECT "users" LIMIT 5`, sql)
+		sql, _ = build(query, map[string]interface{}{"n": 6})
+		assert.Equal(t, `SELECT "users" LIMIT 6`, sql)
+		assert.Equal(t, 2, dialect.builds)
+	})
+
+	stats := executor.PlanStats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 2, stats.Size)
+}
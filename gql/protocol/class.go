@@ -13,6 +13,38 @@ type Class struct {
 	Fields      map[string]*Field `json:"fields"`             // 字段映射表(包含字段名和列名的索引)
 	Resolver    string            `json:"resolver,omitempty"` // 类级别自定义Resolver
 	IsThrough   bool              `json:"isThrough"`          // 是否为中间表关系表
+	Cost        int               `json:"cost,omitempty"`     // 查询成本权重，0表示使用默认权重1
+
+	Permissions map[string]*Permission `json:"permissions,omitempty"` // 按角色配置的权限规则，为空时不限制访问
+}
+
+// Permission 返回角色在类上的权限规则
+// 类未配置权限时返回空规则和true，表示不限制；配置了权限但未包含该角色时返回false
+func (my *Class) Permission(role string) (*Permission, bool) {
+	if len(my.Permissions) == 0 {
+		return nil, true
+	}
+	p, ok := my.Permissions[role]
+	return p, ok && p != nil
+}
+
+// PrimaryField 返回类的唯一主键字段，没有主键或为联合主键时返回nil
+func (my *Class) PrimaryField() *Field {
+	if len(my.PrimaryKeys) > 1 {
+		return nil
+	}
+	var primary *Field
+	for key, field := range my.Fields {
+		// 跳过列名索引和虚拟字段
+		if key != field.Name || !field.IsPrimary || field.Virtual {
+			continue
+		}
+		if primary != nil {
+			return nil
+		}
+		primary = field
+	}
+	return primary
 }
 
 // AddField 添加字段到类中
@@ -64,5 +96,7 @@ func (my *Class) MarshalJSON() ([]byte, error) {
 		PrimaryKeys: my.PrimaryKeys,
 		Description: my.Description,
 		Resolver:    my.Resolver,
+		Permissions: my.Permissions,
+		Cost:        my.Cost,
 	})
 }
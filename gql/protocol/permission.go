@@ -0,0 +1,38 @@
+package protocol
+
+import "slices"
+
+// 权限规则中的操作类型
+const (
+	OP_QUERY  = "query"
+	OP_CREATE = "create"
+	OP_UPDATE = "update"
+	OP_DELETE = "delete"
+)
+
+// Permission 表示角色在类上的权限规则
+type Permission struct {
+	Operations    []string               `json:"operations"`              // 允许的操作
+	Filter        map[string]interface{} `json:"filter,omitempty"`        // 行过滤条件模板，格式与where参数一致
+	IncludeFields []string               `json:"includeFields,omitempty"` // 仅允许访问的字段，为空表示全部
+	ExcludeFields []string               `json:"excludeFields,omitempty"` // 禁止访问的字段
+}
+
+// Allow 判断是否允许执行操作，空规则表示不限制
+func (my *Permission) Allow(op string) bool {
+	return my == nil || slices.Contains(my.Operations, op)
+}
+
+// Visible 判断字段是否允许访问，字段名和列名均可匹配
+func (my *Permission) Visible(field *Field) bool {
+	if my == nil || field == nil {
+		return true
+	}
+	match := func(list []string) bool {
+		return slices.Contains(list, field.Name) || (field.Column != "" && slices.Contains(list, field.Column))
+	}
+	if len(my.IncludeFields) > 0 && !match(my.IncludeFields) {
+		return false
+	}
+	return !match(my.ExcludeFields)
+}
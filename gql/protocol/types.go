@@ -17,3 +17,9 @@ type Loader interface {
 	Support() bool
 	Priority() int
 }
+
+// Reporter 定义问题收集接口
+// Hoster可选实现，加载器通过它上报不影响加载但需要在严格模式下暴露的问题
+type Reporter interface {
+	Report(format string, args ...interface{})
+}
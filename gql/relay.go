@@ -0,0 +1,383 @@
+package gql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/ichaly/ideabase/gql/renderer"
+	"github.com/ichaly/ideabase/std"
+	"github.com/jinzhu/inflection"
+	"github.com/samber/lo"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Relay规范相关常量
+const (
+	TYPE_NODE = "Node"
+	NODE      = "node"
+	NODES     = "nodes"
+	IDS       = "ids"
+	TYPENAME  = "__typename"
+
+	// 全局ID中类名与主键的分隔符
+	globalIdSeparator = ":"
+
+	// 节点查询中追加的主键字段别名，用于将记录对应到请求的ID
+	nodeKeyAlias = "__key"
+)
+
+// EncodeGlobalId 将类名和主键编码为全局唯一ID
+// 数字主键复用std.Id的短ID编码，其他主键保持原样，整体再做URL安全的Base64编码
+func EncodeGlobalId(className string, pk any) string {
+	token := toString(pk)
+	if id, err := strconv.ParseUint(token, 10, 64); err == nil && id > 0 {
+		token = std.Id(id).Encode()
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(className + globalIdSeparator + token))
+}
+
+// DecodeGlobalId 将全局ID解析为类名和主键
+// 短ID主键会被还原为十进制字符串，便于直接作为SQL参数使用
+func DecodeGlobalId(globalId string) (string, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(globalId)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的全局ID: %s", globalId)
+	}
+	className, token, ok := strings.Cut(string(data), globalIdSeparator)
+	if !ok || className == "" || token == "" {
+		return "", "", fmt.Errorf("无效的全局ID: %s", globalId)
+	}
+	var id std.Id
+	if err := id.Decode(token); err == nil && id > 0 {
+		return className, strconv.FormatUint(uint64(id), 10), nil
+	}
+	return className, token, nil
+}
+
+// toString 将主键值转换为字符串，避免浮点数被格式化为科学计数法
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// IsNode 判断类是否实现Node接口
+// 仅在开启relay时，单主键的类才实现Node接口，全局ID由主键字段生成
+func (my *Metadata) IsNode(className string) bool {
+	return my.nodeKey(className) != nil
+}
+
+// nodeKey 返回实现Node接口的类的主键字段，不实现时返回nil
+// 主键字段名不是id时，id字段映射到主键，因此类中不能有其他名为id的字段
+func (my *Metadata) nodeKey(className string) *protocol.Field {
+	if my.cfg == nil || !my.cfg.Schema.Relay {
+		return nil
+	}
+	class, ok := my.Nodes[className]
+	if !ok || class.Name != className || class.Virtual {
+		return nil
+	}
+	if class.IsThrough && !my.cfg.Metadata.ShowThrough {
+		return nil
+	}
+	key := class.PrimaryField()
+	if key == nil {
+		return nil
+	}
+	if field, ok := class.Fields[ID]; ok && field != key {
+		return nil
+	}
+	return key
+}
+
+// renderNode 渲染Relay的Node接口
+func (my *Renderer) renderNode() error {
+	if !my.meta.cfg.Schema.Relay {
+		return nil
+	}
+	my.writeLine("# ", DESC_NODE)
+	my.writeLine("interface ", TYPE_NODE, " {")
+	my.writeField(ID, SCALAR_ID, renderer.NonNull(), renderer.WithComment(COMMENT_GLOBAL_ID))
+	my.writeLine("}")
+	my.writeLine()
+	return nil
+}
+
+// resolveNodes 解析查询中的node和nodes根字段
+// 返回已解析的数据以及剩余需要交给编译器处理的选择集
+func (my *Executor) resolveNodes(ctx context.Context, operation *ast.OperationDefinition, variables map[string]interface{}) (map[string]interface{}, ast.SelectionSet, gqlerror.List) {
+	data := make(map[string]interface{})
+	rest := make(ast.SelectionSet, 0, len(operation.SelectionSet))
+	var errs gqlerror.List
+
+	for _, s := range operation.SelectionSet {
+		field, ok := s.(*ast.Field)
+		if !ok || (field.Name != NODE && field.Name != NODES) {
+			rest = append(rest, s)
+			continue
+		}
+		args := field.ArgumentMap(variables)
+		switch field.Name {
+		case NODE:
+			list, failures := my.fetchNodes(ctx, []string{toString(args[ID])}, field, variables)
+			if failures[0] != nil {
+				errs = append(errs, my.fieldError(field, failures[0]))
+			}
+			data[field.Alias] = list[0]
+		case NODES:
+			ids, _ := args[IDS].([]interface{})
+			list, failures := my.fetchNodes(ctx, lo.Map(ids, func(id interface{}, _ int) string { return toString(id) }), field, variables)
+			for i, err := range failures {
+				if err != nil {
+					e := my.fieldError(field, err)
+					e.Path = append(e.Path, ast.PathIndex(i))
+					errs = append(errs, e)
+				}
+			}
+			data[field.Alias] = list
+		}
+	}
+	return data, rest, errs
+}
+
+// nodeBatch 同一类中待查询的主键，以及每个主键在请求ID列表中的位置
+type nodeBatch struct {
+	keys    []string
+	indexes map[string][]int
+}
+
+// fetchNodes 根据全局ID批量查询节点，同一类的节点合并为一次IN查询，结果和错误按ID的顺序返回
+func (my *Executor) fetchNodes(ctx context.Context, globalIds []string, field *ast.Field, variables map[string]interface{}) ([]interface{}, []error) {
+	list := make([]interface{}, len(globalIds))
+	errs := make([]error, len(globalIds))
+
+	// 按类分组，保持类和主键首次出现的顺序
+	var classes []string
+	batches := make(map[string]*nodeBatch)
+	for i, globalId := range globalIds {
+		className, pk, err := DecodeGlobalId(globalId)
+		if err == nil && !my.metadata.IsNode(className) {
+			err = fmt.Errorf("类%s未实现%s接口", className, TYPE_NODE)
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		batch, ok := batches[className]
+		if !ok {
+			batch = &nodeBatch{indexes: make(map[string][]int)}
+			batches[className] = batch
+			classes = append(classes, className)
+		}
+		if _, ok := batch.indexes[pk]; !ok {
+			batch.keys = append(batch.keys, pk)
+		}
+		batch.indexes[pk] = append(batch.indexes[pk], i)
+	}
+
+	for _, className := range classes {
+		batch := batches[className]
+		items, err := my.queryNodes(ctx, className, batch.keys, field, variables)
+		for pk, indexes := range batch.indexes {
+			for _, i := range indexes {
+				if err != nil {
+					errs[i] = err
+				} else if item, ok := items[pk]; ok {
+					list[i] = item
+				}
+			}
+		}
+	}
+	return list, errs
+}
+
+// queryNodes 查询同一类中的多个节点，返回以主键为键的记录
+func (my *Executor) queryNodes(ctx context.Context, className string, keys []string, field *ast.Field, variables map[string]interface{}) (map[string]map[string]interface{}, error) {
+	key := my.metadata.nodeKey(className)
+	def := my.schema.Types[className]
+	queryName := strcase.ToLowerCamel(inflection.Plural(className))
+	queryDef := my.schema.Query.Fields.ForName(queryName)
+	if key == nil || def == nil || queryDef == nil {
+		return nil, fmt.Errorf("未找到类%s的查询定义", className)
+	}
+	resultDef := my.schema.Types[queryDef.Type.Name()]
+
+	// 将node选择集展开为具体类型的选择集，追加主键用于将记录对应到请求的ID
+	selection := flattenSelection(field.SelectionSet, def)
+	items := append(selection[:len(selection):len(selection)], &ast.Field{
+		Alias:            nodeKeyAlias,
+		Name:             key.Name,
+		Definition:       def.Fields.ForName(key.Name),
+		ObjectDefinition: def,
+	})
+
+	// 包装为按主键IN过滤的列表查询，limit与主键数量一致，避免被默认分页截断
+	values := &ast.Value{Kind: ast.ListValue}
+	for _, pk := range keys {
+		values.Children = append(values.Children, &ast.ChildValue{Value: &ast.Value{Kind: ast.StringValue, Raw: pk}})
+	}
+	query := &ast.Field{
+		Alias:            NODES,
+		Name:             queryName,
+		Definition:       queryDef,
+		ObjectDefinition: my.schema.Query,
+		Arguments: ast.ArgumentList{
+			{Name: WHERE, Value: &ast.Value{Kind: ast.ObjectValue, Children: ast.ChildValueList{{
+				Name: key.Name,
+				Value: &ast.Value{
+					Kind:       ast.ObjectValue,
+					Definition: my.schema.Types[className+SUFFIX_WHERE_INPUT],
+					Children:   ast.ChildValueList{{Name: IN, Value: values}},
+				},
+			}}}},
+			{Name: LIMIT, Value: &ast.Value{Kind: ast.IntValue, Raw: strconv.Itoa(len(keys))}},
+		},
+		SelectionSet: ast.SelectionSet{&ast.Field{
+			Alias:            ITEMS,
+			Name:             ITEMS,
+			Definition:       resultDef.Fields.ForName(ITEMS),
+			ObjectDefinition: resultDef,
+			SelectionSet:     items,
+		}},
+	}
+	r := my.runOperation(ctx, nil, &ast.OperationDefinition{
+		Operation:    ast.Query,
+		SelectionSet: ast.SelectionSet{query},
+	}, variables)
+	if len(r.Errors) > 0 {
+		return nil, r.Errors
+	}
+
+	result, _ := r.Data[NODES].(map[string]interface{})
+	rows, _ := result[ITEMS].([]interface{})
+	nodes := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		item, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pk := toString(item[nodeKeyAlias])
+		delete(item, nodeKeyAlias)
+		for _, s := range selection {
+			if f, ok := s.(*ast.Field); ok && f.Name == TYPENAME {
+				item[f.Alias] = className
+			}
+		}
+		my.encodeNodeIds(selection, item)
+		nodes[pk] = item
+	}
+	return nodes, nil
+}
+
+// flattenSelection 将接口上的选择集(含内联片段和片段引用)展开为具体类型的字段列表
+func flattenSelection(set ast.SelectionSet, def *ast.Definition) ast.SelectionSet {
+	list := make(ast.SelectionSet, 0, len(set))
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.Field:
+			f := *v
+			f.ObjectDefinition = def
+			if fd := def.Fields.ForName(v.Name); fd != nil {
+				f.Definition = fd
+			}
+			list = append(list, &f)
+		case *ast.InlineFragment:
+			if v.TypeCondition == "" || v.TypeCondition == def.Name || v.TypeCondition == TYPE_NODE {
+				list = append(list, flattenSelection(v.SelectionSet, def)...)
+			}
+		case *ast.FragmentSpread:
+			if v.Definition != nil && (v.Definition.TypeCondition == def.Name || v.Definition.TypeCondition == TYPE_NODE) {
+				list = append(list, flattenSelection(v.Definition.SelectionSet, def)...)
+			}
+		}
+	}
+	return list
+}
+
+// encodeNodeIds 将结果中Node类型对象的id字段替换为全局ID
+func (my *Executor) encodeNodeIds(set ast.SelectionSet, value interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			my.encodeNodeIds(set, item)
+		}
+	case map[string]interface{}:
+		for _, s := range set {
+			switch f := s.(type) {
+			case *ast.Field:
+				child, ok := v[f.Alias]
+				if !ok || child == nil {
+					continue
+				}
+				if f.Name == ID && f.ObjectDefinition != nil && my.metadata.IsNode(f.ObjectDefinition.Name) {
+					v[f.Alias] = EncodeGlobalId(f.ObjectDefinition.Name, child)
+				} else if len(f.SelectionSet) > 0 {
+					my.encodeNodeIds(f.SelectionSet, child)
+				}
+			case *ast.InlineFragment:
+				my.encodeNodeIds(f.SelectionSet, v)
+			case *ast.FragmentSpread:
+				if f.Definition != nil {
+					my.encodeNodeIds(f.Definition.SelectionSet, v)
+				}
+			}
+		}
+	}
+}
+
+// decodeNodeArgs 将根字段id参数中的全局ID还原为主键
+// 返回替换后的变量副本，原始变量不会被修改
+func (my *Executor) decodeNodeArgs(operation *ast.OperationDefinition, variables map[string]interface{}) map[string]interface{} {
+	decode := func(raw string) (string, bool) {
+		className, pk, err := DecodeGlobalId(raw)
+		if err != nil || !my.metadata.IsNode(className) {
+			return "", false
+		}
+		return pk, true
+	}
+
+	copied := false
+	for _, s := range operation.SelectionSet {
+		field, ok := s.(*ast.Field)
+		if !ok || field.Name == NODE || field.Name == NODES {
+			continue
+		}
+		arg := field.Arguments.ForName(ID)
+		if arg == nil || arg.Value == nil {
+			continue
+		}
+		switch arg.Value.Kind {
+		case ast.StringValue:
+			if pk, ok := decode(arg.Value.Raw); ok {
+				arg.Value.Raw = pk
+			}
+		case ast.Variable:
+			raw, ok := variables[arg.Value.Raw].(string)
+			if !ok {
+				continue
+			}
+			if pk, ok := decode(raw); ok {
+				if !copied {
+					variables = lo.Assign(variables)
+					copied = true
+				}
+				variables[arg.Value.Raw] = pk
+			}
+		}
+	}
+	return variables
+}
@@ -0,0 +1,159 @@
+package gql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ichaly/ideabase/gql/compiler"
+	"github.com/ichaly/ideabase/gql/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// newMockExecutor 基于模拟元数据创建执行器，schema文件写入临时目录
+func newMockExecutor(t *testing.T, meta *Metadata) *Executor {
+	meta.cfg.Root = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(meta.cfg.Root, "cfg"), 0755))
+	executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil)
+	require.NoError(t, err, "创建执行器失败")
+	return executor
+}
+
+// addTagClass 添加主键字段名不是id的Tag类和联合主键的Like类
+func addTagClass(meta *Metadata) {
+	tag := &protocol.Class{Name: "Tag", Table: "tags", PrimaryKeys: []string{"code"}, Fields: make(map[string]*protocol.Field)}
+	tag.AddField(&protocol.Field{Name: "code", Column: "code", Type: "text", IsPrimary: true})
+	tag.AddField(&protocol.Field{Name: "label", Column: "label", Type: "text"})
+	meta.Nodes[tag.Name] = tag
+
+	like := &protocol.Class{Name: "Like", Table: "likes", PrimaryKeys: []string{"user_id", "post_id"}, Fields: make(map[string]*protocol.Field)}
+	like.AddField(&protocol.Field{Name: "userId", Column: "user_id", Type: "integer", IsPrimary: true})
+	like.AddField(&protocol.Field{Name: "postId", Column: "post_id", Type: "integer", IsPrimary: true})
+	meta.Nodes[like.Name] = like
+}
+
+func TestRelayGlobalId(t *testing.T) {
+	tests := []struct {
+		name  string
+		class string
+		pk    any
+		want  string
+	}{
+		{name: "整数主键", class: "User", pk: 1024, want: "1024"},
+		{name: "JSON数字主键", class: "User", pk: float64(987654321012), want: "987654321012"},
+		{name: "字符串主键", class: "Tag", pk: "golang", want: "golang"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := EncodeGlobalId(tt.class, tt.pk)
+			className, pk, err := DecodeGlobalId(id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.class, className)
+			assert.Equal(t, tt.want, pk)
+		})
+	}
+
+	_, _, err := DecodeGlobalId("not-a-global-id")
+	assert.Error(t, err)
+}
+
+func TestRelaySchema(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Schema.Relay = true
+	executor := newMockExecutor(t, meta)
+
+	assert.Contains(t, executor.schema.Types["User"].Interfaces, TYPE_NODE)
+	assert.Contains(t, executor.schema.Types["Post"].Interfaces, TYPE_NODE)
+	assert.NotNil(t, executor.schema.Query.Fields.ForName(NODE))
+	assert.NotNil(t, executor.schema.Query.Fields.ForName(NODES))
+
+	// 主键字段名不是id的类同样实现Node接口，id字段映射到主键
+	meta = createMockMetadata(t)
+	meta.cfg.Schema.Relay = true
+	addTagClass(meta)
+	executor = newMockExecutor(t, meta)
+	assert.Contains(t, executor.schema.Types["Tag"].Interfaces, TYPE_NODE)
+	assert.NotNil(t, executor.schema.Types["Tag"].Fields.ForName(ID))
+	assert.NotContains(t, executor.schema.Types["Like"].Interfaces, TYPE_NODE, "联合主键的类不实现Node接口")
+	field, ok := compiler.NewContext(meta, `"`, nil).FindField("Tag", ID)
+	require.True(t, ok)
+	assert.Equal(t, "code", field.Column)
+
+	// 未开启relay时不生成Node接口
+	meta = createMockMetadata(t)
+	executor = newMockExecutor(t, meta)
+	assert.Nil(t, executor.schema.Types[TYPE_NODE])
+	assert.Nil(t, executor.schema.Query.Fields.ForName(NODE))
+}
+
+func TestRelayNodeIds(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Schema.Relay = true
+	executor := newMockExecutor(t, meta)
+
+	doc, err := gqlparser.LoadQuery(executor.schema, `query($id: ID) { users(id: $id) { items { key: id name } } }`)
+	require.Nil(t, err)
+	operation := doc.Operations[0]
+
+	// 结果中的id被替换为全局ID
+	data := map[string]interface{}{
+		"users": map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{"key": float64(7), "name": "Tom"}},
+		},
+	}
+	executor.encodeNodeIds(operation.SelectionSet, data)
+	item := data["users"].(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, EncodeGlobalId("User", 7), item["key"])
+	assert.Equal(t, "Tom", item["name"])
+
+	// 参数中的全局ID被还原为主键，且不修改原始变量
+	variables := map[string]interface{}{"id": EncodeGlobalId("User", 7)}
+	decoded := executor.decodeNodeArgs(operation, variables)
+	assert.Equal(t, "7", decoded["id"])
+	assert.Equal(t, EncodeGlobalId("User", 7), variables["id"])
+}
+
+func TestRelayNodes(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Schema.Relay = true
+	addTagClass(meta)
+	executor := newMockExecutor(t, meta)
+	executor.compiler = &Compiler{meta: meta, dialect: &countingDialect{}}
+	var queries []string
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		queries = append(queries, query)
+		if strings.Contains(query, `"tags"`) {
+			return `{"nodes":{"items":[{"__key":"golang","id":"golang","label":"Go"}]}}`, nil
+		}
+		return `{"nodes":{"items":[{"__key":9,"id":9,"name":"Jerry"},{"__key":7,"id":7,"name":"Tom"}]}}`, nil
+	})
+
+	ids := []interface{}{EncodeGlobalId("User", 7), EncodeGlobalId("Tag", "golang"), EncodeGlobalId("User", 8), "bad", EncodeGlobalId("User", 9), EncodeGlobalId("User", 7)}
+	r := executor.Execute(context.Background(), `query($ids: [ID!]!) {
+		nodes(ids: $ids) { id __typename ... on User { name } ... on Tag { label } }
+	}`, map[string]interface{}{"ids": ids}, "")
+
+	assert.Equal(t, []string{`SELECT "users" LIMIT 3`, `SELECT "tags" LIMIT 1`}, queries, "同一类的节点合并为一次查询")
+	tom := map[string]interface{}{"id": EncodeGlobalId("User", 7), "__typename": "User", "name": "Tom"}
+	assert.Equal(t, []interface{}{
+		tom,
+		map[string]interface{}{"id": EncodeGlobalId("Tag", "golang"), "__typename": "Tag", "label": "Go"},
+		nil,
+		nil,
+		map[string]interface{}{"id": EncodeGlobalId("User", 9), "__typename": "User", "name": "Jerry"},
+		tom,
+	}, r.Data[NODES], "结果按ID的顺序返回，不存在的节点为null")
+	require.Len(t, r.Errors, 1, "无效的全局ID")
+	assert.Equal(t, ast.Path{ast.PathName(NODES), ast.PathIndex(3)}, r.Errors[0].Path)
+
+	queries = nil
+	r = executor.Execute(context.Background(), `query($id: ID!) { node(id: $id) { id ... on Tag { label } } }`, map[string]interface{}{"id": EncodeGlobalId("Tag", "golang")}, "")
+	require.Empty(t, r.Errors)
+	assert.Equal(t, map[string]interface{}{"id": EncodeGlobalId("Tag", "golang"), "label": "Go"}, r.Data[NODE])
+	assert.Equal(t, []string{`SELECT "tags" LIMIT 1`}, queries)
+}
@@ -0,0 +1,238 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ichaly/ideabase/gql/internal/intro"
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std"
+	"github.com/knadh/koanf/v2"
+)
+
+// 热加载触发的防抖时间，合并短时间内的多次变更
+const reloadDebounce = 200 * time.Millisecond
+
+// Reload 重新构建元数据和schema，成功后原子替换当前快照
+// 构建失败时保留正在使用的schema，进行中的请求继续在旧快照上完成
+func (my *Executor) Reload() error {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+
+	current := my.current.Load()
+	meta, err := current.metadata.Reload()
+	if err != nil {
+		return fmt.Errorf("重建元数据失败: %w", err)
+	}
+
+	// 可信文档清单独立于schema，每次都重新加载，失败时保留当前清单
+	if err = current.trusted.load(meta.cfg); err != nil {
+		log.Error().Err(err).Msg("重新加载可信文档清单失败，继续使用当前清单")
+	}
+
+	// 元数据和schema配置均未变化时无需替换
+	fingerprint := meta.fingerprint()
+	if fingerprint == current.fingerprint {
+		log.Debug().Msg("元数据未发生变化，跳过schema替换")
+		return nil
+	}
+
+	schema, err := loadSchema(NewRenderer(meta))
+	if err != nil {
+		return fmt.Errorf("重建schema失败: %w", err)
+	}
+
+	roles, err := loadRoleSchemas(meta)
+	if err != nil {
+		return fmt.Errorf("重建schema失败: %w", err)
+	}
+
+	next := *current
+	next.schema = schema
+	next.roles = roles
+	next.intro = intro.New(schema)
+	next.metadata = meta
+	next.compiler = current.compiler.fork(meta)
+	next.fingerprint = fingerprint
+	next.plans = current.plans.fork(meta.cfg.Executor.Plan.Size)
+	my.current.Store(&next)
+
+	log.Info().Str("version", meta.Version).Msg("schema已热加载")
+	return nil
+}
+
+// ReloadTrusted 按当前配置重新加载可信文档清单，失败时保留当前清单
+func (my *Executor) ReloadTrusted() error {
+	if err := my.trusted.load(my.current.Load().metadata.cfg); err != nil {
+		return fmt.Errorf("重新加载可信文档清单失败: %w", err)
+	}
+	return nil
+}
+
+// Reloader 元数据热加载器
+// 监听配置文件、元数据文件变更或按固定间隔触发执行器重建schema，可信文档清单变更时只重新加载清单
+type Reloader struct {
+	k        *std.Konfig
+	executor *Executor
+	watcher  *fsnotify.Watcher
+	timer    *time.Timer
+	trusted  *time.Timer
+	stop     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewReloader 创建热加载器并注册到应用生命周期
+func NewReloader(k *std.Konfig, e *Executor, l std.Lifecycle) *Reloader {
+	my := &Reloader{k: k, executor: e}
+	l.Append(my.Start, my.Stop)
+	return my
+}
+
+// Start 根据配置启动文件监听与定时重建
+func (my *Reloader) Start(_ context.Context) error {
+	cfg := my.executor.current.Load().metadata.cfg
+	if cfg == nil {
+		return nil
+	}
+	my.stop = make(chan struct{})
+
+	// 可信文档清单独立于元数据热加载，始终监听，更新清单无需重启
+	files := map[string]func(){ResolveManifestPath(cfg): my.TriggerTrusted}
+	if cfg.Metadata.Reload.Watch {
+		// 配置变更会影响类型映射、命名规范和配置类
+		my.k.OnConfigChange(func(*koanf.Koanf) { my.Trigger("config") })
+		if err := my.k.WatchConfig(); err != nil {
+			log.Warn().Err(err).Msg("启动配置文件监听失败")
+		}
+		// 非调试模式下元数据来自文件，需要监听文件变更
+		if !cfg.IsDebug() {
+			files[metadata.ResolveMetadataPath(cfg)] = func() { my.Trigger("file") }
+		}
+	}
+	if err := my.watchFile(files); err != nil {
+		log.Warn().Err(err).Msg("启动文件监听失败")
+	}
+
+	if interval := cfg.Metadata.Reload.Interval; interval > 0 {
+		go my.loop(interval)
+	}
+	return nil
+}
+
+// Stop 停止所有监听
+func (my *Reloader) Stop(_ context.Context) error {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if my.stop != nil {
+		close(my.stop)
+		my.stop = nil
+	}
+	if my.timer != nil {
+		my.timer.Stop()
+	}
+	if my.trusted != nil {
+		my.trusted.Stop()
+	}
+	if my.watcher != nil {
+		_ = my.watcher.Close()
+		my.watcher = nil
+	}
+	return nil
+}
+
+// Trigger 按需触发一次重建，短时间内的多次触发会被合并
+func (my *Reloader) Trigger(reason string) {
+	my.debounce(&my.timer, func() {
+		if err := my.executor.Reload(); err != nil {
+			log.Error().Err(err).Str("reason", reason).Msg("schema热加载失败，继续使用当前schema")
+		}
+	})
+}
+
+// TriggerTrusted 按需重新加载可信文档清单，短时间内的多次触发会被合并
+func (my *Reloader) TriggerTrusted() {
+	my.debounce(&my.trusted, func() {
+		if err := my.executor.ReloadTrusted(); err != nil {
+			log.Error().Err(err).Msg("可信文档清单热加载失败，继续使用当前清单")
+		}
+	})
+}
+
+// debounce 延迟执行，等待期间再次触发时重新计时
+func (my *Reloader) debounce(timer **time.Timer, fn func()) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if *timer != nil {
+		(*timer).Stop()
+	}
+	*timer = time.AfterFunc(reloadDebounce, fn)
+}
+
+// loop 定时重建，用于感知数据库结构变化
+func (my *Reloader) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stop := my.stop
+	for {
+		select {
+		case <-ticker.C:
+			my.Trigger("interval")
+		case <-stop:
+			return
+		}
+	}
+}
+
+// watchFile 监听文件所在目录，兼容编辑器先删除再创建的保存方式，文件变更时执行对应的处理
+func (my *Reloader) watchFile(files map[string]func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]func(), len(files))
+	for path, handle := range files {
+		dir := filepath.Dir(path)
+		if _, err = os.Stat(dir); err != nil {
+			continue
+		}
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return err
+		}
+		targets[filepath.Clean(path)] = handle
+		log.Info().Str("file", path).Msg("已启动文件监听")
+	}
+	my.mu.Lock()
+	my.watcher = watcher
+	my.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				handle, ok := targets[filepath.Clean(event.Name)]
+				if !ok {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					handle()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Err(err).Msg("文件监听错误")
+			}
+		}
+	}()
+	return nil
+}
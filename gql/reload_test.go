@@ -0,0 +1,107 @@
+package gql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadSchema(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "cfg"), 0755))
+
+	k, err := std.NewKonfig()
+	require.NoError(t, err)
+	k.Set("mode", "test")
+	k.Set("app.root", root)
+	k.Set("metadata.classes", map[string]*internal.ClassConfig{
+		"User": {
+			Table: "users",
+			Fields: map[string]*internal.FieldConfig{
+				"id":   {Column: "id", Type: "int", IsPrimary: true},
+				"name": {Column: "name", Type: "varchar"},
+			},
+		},
+	})
+
+	meta, err := NewMetadata(k, nil, WithoutLoader(metadata.LoaderFile))
+	require.NoError(t, err)
+	executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil)
+	require.NoError(t, err)
+	origin := executor.current.Load()
+
+	// 无变化时不替换快照
+	require.NoError(t, executor.Reload())
+	assert.Same(t, origin, executor.current.Load())
+
+	// 新增类后原子替换为新快照，旧快照保持不变
+	k.Set("metadata.classes", map[string]*internal.ClassConfig{
+		"Tag": {
+			Table: "tags",
+			Fields: map[string]*internal.FieldConfig{
+				"id": {Column: "id", Type: "int", IsPrimary: true},
+			},
+		},
+	})
+	require.NoError(t, executor.Reload())
+	current := executor.current.Load()
+	assert.NotSame(t, origin, current)
+	assert.NotNil(t, current.schema.Types["Tag"])
+	assert.Nil(t, origin.schema.Types["Tag"])
+
+	// 只修改执行器配置时同样替换快照
+	k.Set("executor.timeout", "5s")
+	k.Set("executor.trusted.enable", true)
+	require.NoError(t, executor.Reload())
+	assert.NotSame(t, current, executor.current.Load())
+	current = executor.current.Load()
+	assert.Equal(t, 5*time.Second, current.metadata.cfg.Executor.Timeout)
+	assert.True(t, current.metadata.cfg.Executor.Trusted.Enable)
+
+	// 重建失败时保留当前schema
+	k.Set("metadata.classes", map[string]*internal.ClassConfig{
+		"Bad-Class": {
+			Table:    "bad_class",
+			Override: true,
+			Fields: map[string]*internal.FieldConfig{
+				"id": {Column: "id", Type: "int", IsPrimary: true},
+			},
+		},
+	})
+	assert.Error(t, executor.Reload())
+	assert.Same(t, current, executor.current.Load())
+}
+
+func TestReloaderWatchTrusted(t *testing.T) {
+	meta := createMockMetadata(t)
+	executor := newMockExecutor(t, meta)
+	require.False(t, meta.cfg.Metadata.Reload.Watch)
+	origin := executor.current.Load()
+
+	reloader := &Reloader{executor: executor}
+	require.NoError(t, reloader.Start(context.Background()))
+	defer reloader.Stop(context.Background())
+
+	// 未开启元数据热加载时，清单变更同样生效，且不重建schema
+	path := ResolveManifestPath(meta.cfg)
+	require.NoError(t, os.WriteFile(path, []byte(`{"list": "query list { users { total } }"}`), 0644))
+	assert.Eventually(t, func() bool {
+		_, ok := executor.trusted.lookup("list")
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Same(t, origin, executor.current.Load())
+
+	// 清单无效时保留当前清单
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0644))
+	time.Sleep(3 * reloadDebounce)
+	_, ok := executor.trusted.lookup("list")
+	assert.True(t, ok)
+}
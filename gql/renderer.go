@@ -32,6 +32,7 @@ const (
 	DESC_NUMBER_STATS    = "数值聚合结果"
 	DESC_STRING_STATS    = "字符串聚合结果"
 	DESC_DATE_TIME_STATS = "日期聚合结果"
+	DESC_NODE            = "Relay节点接口，所有可按全局ID获取的对象均实现该接口"
 
 	// 分类标题
 	SECTION_PAGING      = "分页相关类型"
@@ -62,6 +63,7 @@ const (
 	COMMENT_MAX_STRING   = "最大值(按字典序)"
 	COMMENT_MIN_DATE     = "最早时间"
 	COMMENT_MAX_DATE     = "最晚时间"
+	COMMENT_GLOBAL_ID    = "全局唯一ID"
 )
 
 // Renderer 负责将元数据渲染为GraphQL schema
@@ -93,8 +95,11 @@ func (my *Renderer) Generate() (string, error) {
 		fn   func() error
 	}{
 		{"标量类型", my.renderScalars},
+		{"实时查询指令", my.renderLive},
+		{"执行计划指令", my.renderExplain},
 		{"枚举类型", my.renderEnums},
 		{"通用类型", my.renderCommon},
+		{"节点接口", my.renderNode},
 		{"实体类型", my.renderTypes},
 		{"分页类型", my.renderPaging},
 		{"统计类型", my.renderStats},
@@ -104,6 +109,7 @@ func (my *Renderer) Generate() (string, error) {
 		{"输入类型", my.renderInput},
 		{"查询根类型", my.renderQuery},
 		{"变更根类型", my.renderMutation},
+		{"订阅根类型", my.renderSubscription},
 	}
 
 	// 遍历执行所有渲染函数
@@ -137,10 +143,14 @@ func (my *Renderer) write(parts ...string) {
 	}
 }
 
-// saveToFile 将生成的Schema保存到文件
+// saveToFile 将生成的Schema保存到文件，角色专属的schema保存为schema.<角色>.graphql
 func (my *Renderer) saveToFile(content string) error {
 	// 写入文件
-	filename := filepath.Join(my.meta.cfg.Root, "cfg/schema.graphql")
+	name := "cfg/schema.graphql"
+	if my.meta.role != "" {
+		name = fmt.Sprintf("cfg/schema.%s.graphql", my.meta.role)
+	}
+	filename := filepath.Join(my.meta.cfg.Root, name)
 	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 		return fmt.Errorf("写入schema文件失败: %w", err)
 	}
@@ -231,8 +241,16 @@ func (my *Renderer) renderTypes() error {
 			my.writeLine("# ", class.Description)
 		}
 
-		// 开始类型定义
-		my.writeLine("type ", className, " {")
+		// 开始类型定义，实现Node接口的类追加implements声明
+		if my.meta.IsNode(className) {
+			my.writeLine("type ", className, " implements ", TYPE_NODE, " {")
+			// 主键字段名不是id时，补充Node接口要求的id字段，由主键生成全局ID
+			if _, ok := class.Fields[ID]; !ok {
+				my.writeField(ID, SCALAR_ID, renderer.NonNull(), renderer.WithComment(COMMENT_GLOBAL_ID))
+			}
+		} else {
+			my.writeLine("type ", className, " {")
+		}
 
 		// 添加所有字段，确保只处理真正的字段名
 		fields := utl.SortKeys(class.Fields)
@@ -628,8 +646,34 @@ func (my *Renderer) renderQuery() error {
 	my.writeLine("# ", SEPARATOR_LINE, " ", SECTION_QUERY, " ", SEPARATOR_LINE, "\n")
 	my.writeLine("# 查询根类型")
 	my.writeLine("type Query {")
+	my.renderQueryFields()
+
+	// Relay节点查询
+	if my.meta.cfg.Schema.Relay {
+		my.writeLine("  # 根据全局ID查询节点")
+		my.writeField(NODE, TYPE_NODE, renderer.WithArgs(renderer.Argument{Name: ID, Type: SCALAR_ID + "!"}))
+		my.writeLine("  # 根据全局ID批量查询节点")
+		my.writeField(NODES, "["+TYPE_NODE+"]!", renderer.WithArgs(renderer.Argument{Name: IDS, Type: "[" + SCALAR_ID + "!]!"}))
+	}
+
+	my.writeLine("}")
+	my.writeLine()
+	return nil
+}
+
+// renderSubscription 渲染订阅根类型
+// 订阅字段与查询字段一致，相关表发生变更时重新执行并推送结果
+func (my *Renderer) renderSubscription() error {
+	my.writeLine("# 订阅根类型")
+	my.writeLine("type Subscription {")
+	my.renderQueryFields()
+	my.writeLine("}")
+	my.writeLine()
+	return nil
+}
 
-	// 为每个实体类生成查询字段
+// renderQueryFields 为每个实体类生成列表查询和统计查询字段
+func (my *Renderer) renderQueryFields() {
 	keys := utl.SortKeys(my.meta.Nodes)
 	for _, className := range keys {
 		class := my.meta.Nodes[className]
@@ -643,6 +687,11 @@ func (my *Renderer) renderQuery() error {
 			continue
 		}
 
+		// 角色视图只渲染有查询权限的类
+		if !my.meta.allow(class, protocol.OP_QUERY) {
+			continue
+		}
+
 		// 统一查询（支持单条和多条）
 		my.writeLine("  # ", className, "查询")
 		my.writeField(
@@ -675,18 +724,13 @@ func (my *Renderer) renderQuery() error {
 			}...),
 		)
 	}
-
-	my.writeLine("}")
-	my.writeLine()
-	return nil
 }
 
 // renderMutation 渲染变更根类型
+// 角色视图只渲染有对应权限的变更，没有任何变更时不生成变更根类型
 func (my *Renderer) renderMutation() error {
-	my.writeLine("# 突变根类型")
-	my.writeLine("type Mutation {")
-
-	// 按排序顺序渲染每种类型的变更操作
+	// 先收集有变更权限的类，GraphQL不允许空的对象类型
+	var classes []*protocol.Class
 	keys := utl.SortKeys(my.meta.Nodes)
 	for _, className := range keys {
 		class := my.meta.Nodes[className]
@@ -698,24 +742,43 @@ func (my *Renderer) renderMutation() error {
 		if class.IsThrough && !my.meta.cfg.Metadata.ShowThrough {
 			continue
 		}
+		if my.meta.allow(class, protocol.OP_CREATE) || my.meta.allow(class, protocol.OP_UPDATE) || my.meta.allow(class, protocol.OP_DELETE) {
+			classes = append(classes, class)
+		}
+	}
+	if len(classes) == 0 {
+		return nil
+	}
 
-		my.writeLine("  # ", class.Name, "创建")
-		my.writeField(CREATE+className, className, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
-			{Name: INPUT, Type: className + SUFFIX_CREATE_INPUT + "!"},
-		}...))
+	my.writeLine("# 突变根类型")
+	my.writeLine("type Mutation {")
 
-		my.writeLine("  # ", class.Name, "更新")
-		my.writeField(UPDATE+className, className, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
-			{Name: INPUT, Type: className + SUFFIX_UPDATE_INPUT + "!"},
-			{Name: ID, Type: SCALAR_ID},
-			{Name: WHERE, Type: className + SUFFIX_WHERE_INPUT},
-		}...))
+	// 按排序顺序渲染每种类型的变更操作
+	for _, class := range classes {
+		className := class.Name
+		if my.meta.allow(class, protocol.OP_CREATE) {
+			my.writeLine("  # ", class.Name, "创建")
+			my.writeField(CREATE+className, className, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
+				{Name: INPUT, Type: className + SUFFIX_CREATE_INPUT + "!"},
+			}...))
+		}
 
-		my.writeLine("  # ", class.Name, "删除")
-		my.writeField(DELETE+className, SCALAR_INT, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
-			{Name: ID, Type: SCALAR_ID},
-			{Name: WHERE, Type: className + SUFFIX_WHERE_INPUT},
-		}...))
+		if my.meta.allow(class, protocol.OP_UPDATE) {
+			my.writeLine("  # ", class.Name, "更新")
+			my.writeField(UPDATE+className, className, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
+				{Name: INPUT, Type: className + SUFFIX_UPDATE_INPUT + "!"},
+				{Name: ID, Type: SCALAR_ID},
+				{Name: WHERE, Type: className + SUFFIX_WHERE_INPUT},
+			}...))
+		}
+
+		if my.meta.allow(class, protocol.OP_DELETE) {
+			my.writeLine("  # ", class.Name, "删除")
+			my.writeField(DELETE+className, SCALAR_INT, renderer.NonNull(), renderer.WithArgs([]renderer.Argument{
+				{Name: ID, Type: SCALAR_ID},
+				{Name: WHERE, Type: className + SUFFIX_WHERE_INPUT},
+			}...))
+		}
 	}
 
 	my.writeLine("}")
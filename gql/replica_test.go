@@ -0,0 +1,56 @@
+package gql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	var primary, replica []string
+	executor := newCountingExecutor(t)
+	db, source := newFakeDatabase(t, func(_ context.Context, query string) (string, error) {
+		primary = append(primary, query)
+		return `{"users":{"total":1},"createUser":{"id":1}}`, nil
+	})
+	connector := &fakeConnector{handle: func(_ context.Context, query string) (string, error) {
+		replica = append(replica, query)
+		return `{"users":{"total":2}}`, nil
+	}}
+	replicas := std.NewReplicas(postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}))
+	require.NoError(t, db.Use(replicas))
+	t.Cleanup(func() { _ = replicas.Close() })
+	executor.database = db
+	// 与生产配置一致：PostgreSQL方言并设置执行超时
+	executor.compiler.dialect = &countingDialect{name: "postgresql"}
+	executor.metadata.cfg.Executor.Timeout = 30 * time.Second
+
+	t.Run("查询路由到副本", func(t *testing.T) {
+		primary, replica = nil, nil
+		source.execs, connector.execs = nil, nil
+		r := executor.Execute(context.Background(), `{ users { total } }`, nil, "")
+		require.Empty(t, r.Errors)
+		assert.Equal(t, map[string]interface{}{"total": float64(2)}, r.Data["users"])
+		assert.Equal(t, []string{`SELECT "users"`}, replica)
+		assert.Empty(t, primary)
+		assert.Equal(t, []string{"SET LOCAL statement_timeout = 30000"}, connector.execs, "设置语句超时的事务同样开启在副本上")
+		assert.Empty(t, source.execs)
+	})
+
+	t.Run("变更使用主库", func(t *testing.T) {
+		primary, replica = nil, nil
+		source.execs, connector.execs = nil, nil
+		r := executor.Execute(context.Background(), `mutation { createUser(input: {id: 1, name: "a", email: "b", createdAt: "2024-01-01T00:00:00Z"}) { id } }`, nil, "")
+		require.Empty(t, r.Errors)
+		assert.Equal(t, []string{`SELECT "createUser"`}, primary)
+		assert.Empty(t, replica)
+		assert.Equal(t, []string{"SET LOCAL statement_timeout = 30000"}, source.execs)
+		assert.Empty(t, connector.execs)
+	})
+}
@@ -0,0 +1,77 @@
+package gql
+
+import (
+	"bufio"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// SSE相关常量，消息格式参考graphql-sse的distinct connections模式
+const (
+	sseContentType = "text/event-stream"
+	sseNext        = "next"
+	sseComplete    = "complete"
+
+	// 未配置keep-alive时探测连接断开的间隔
+	sseProbeInterval = 30 * time.Second
+)
+
+// stream 以SSE推送订阅和实时查询的结果，结果通道关闭后发送complete事件
+// 连接断开只能在写入时发现，因此按keep-alive间隔发送注释行探测，未配置时使用sseProbeInterval
+func (my *Executor) stream(c fiber.Ctx, req gqlQuery) error {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.Context()))
+	ch, err := my.Subscribe(ctx, req.Query, req.Variables, req.OperationName)
+	if err != nil {
+		cancel()
+		errs, ok := err.(gqlerror.List)
+		if !ok {
+			errs = gqlerror.List{gqlerror.Wrap(err)}
+		}
+		return c.JSON(gqlReply{Errors: errs})
+	}
+
+	c.Set(fiber.HeaderContentType, sseContentType)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	interval := my.current.Load().metadata.cfg.Executor.Subscription.KeepAlive
+	if interval <= 0 {
+		interval = sseProbeInterval
+	}
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case r, ok := <-ch:
+				if !ok {
+					_ = writeEvent(w, sseComplete, nil)
+					return
+				}
+				if writeEvent(w, sseNext, mustMarshal(r)) != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := w.WriteString(":\n\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// writeEvent 写入一条SSE事件并立即刷新
+func writeEvent(w *bufio.Writer, name string, data []byte) error {
+	_, _ = w.WriteString("event: " + name + "\ndata: ")
+	_, _ = w.Write(data)
+	if _, err := w.WriteString("\n\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
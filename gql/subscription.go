@@ -0,0 +1,192 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// subscriber 单个订阅，按涉及的表接收变更通知
+type subscriber struct {
+	tables map[string]bool
+	notify chan struct{} // 容量为1，执行期间的多次变更合并为一次重新执行
+}
+
+// broker 订阅中心
+// 每个执行器只在事件总线上订阅一次表变更主题，再按表名分发给进程内的订阅，
+// 取消订阅只需从本地注册表移除，不依赖底层驱动的退订能力
+type broker struct {
+	bus         *event.Bus
+	once        sync.Once
+	mu          sync.RWMutex
+	seq         uint64
+	subscribers map[uint64]*subscriber
+}
+
+func newBroker(bus *event.Bus) *broker {
+	return &broker{bus: bus, subscribers: make(map[uint64]*subscriber)}
+}
+
+// register 注册订阅，返回通知通道和取消函数
+func (my *broker) register(tables map[string]bool) (<-chan struct{}, func()) {
+	my.once.Do(func() {
+		if my.bus == nil {
+			log.Warn().Msg("未配置事件总线，订阅仅推送首次结果")
+			return
+		}
+		err := event.Subscribe(context.Background(), my.bus, event.TopicTableChange, my.dispatch)
+		if err != nil {
+			log.Error().Err(err).Msg("订阅表变更事件失败")
+		}
+	})
+
+	sub := &subscriber{tables: tables, notify: make(chan struct{}, 1)}
+	my.mu.Lock()
+	my.seq++
+	id := my.seq
+	my.subscribers[id] = sub
+	my.mu.Unlock()
+
+	return sub.notify, func() {
+		my.mu.Lock()
+		delete(my.subscribers, id)
+		my.mu.Unlock()
+	}
+}
+
+// publish 广播表变更，未配置事件总线时直接分发给本实例的订阅
+func (my *broker) publish(ctx context.Context, changes []event.TableChange) {
+	for _, change := range changes {
+		if my.bus == nil {
+			_ = my.dispatch(ctx, change)
+			continue
+		}
+		if err := event.Publish(ctx, my.bus, event.TopicTableChange, change); err != nil {
+			log.Warn().Err(err).Str("table", change.Table).Msg("广播表变更失败")
+		}
+	}
+}
+
+// dispatch 将表变更分发给涉及该表的订阅
+func (my *broker) dispatch(_ context.Context, change event.TableChange) error {
+	my.mu.RLock()
+	defer my.mu.RUnlock()
+	for _, sub := range my.subscribers {
+		if !sub.tables[change.Table] {
+			continue
+		}
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe 执行GraphQL订阅或@live实时查询
+// 立即推送一次结果，此后每当涉及的表发生变更时重新执行并推送，结果未变化时不推送；
+// @live(patch: true)时首次推送完整结果，之后只推送相对上次结果的JSON Patch
+// ctx结束时取消订阅并关闭结果通道；普通查询和变更操作执行一次后即关闭通道
+func (my *Executor) Subscribe(ctx context.Context, query string, variables map[string]interface{}, operationName string) (<-chan gqlReply, error) {
+	snapshot := my.current.Load()
+	// 与普通请求一致，按请求角色的schema校验，角色无权访问的类型和字段不能订阅
+	role, _ := snapshot.authorize(ctx, variables)
+	schema, _, ok := snapshot.schemaFor(role)
+	if !ok {
+		return nil, gqlerror.List{newCodeError(CODE_FORBIDDEN, fmt.Sprintf("角色%s无权访问", role))}
+	}
+	doc, errs := gqlparser.LoadQuery(schema, query)
+	if errs != nil {
+		return nil, errs
+	}
+	operation, err := getOperation(doc.Operations, operationName)
+	if err != nil {
+		return nil, gqlerror.Wrap(err)
+	}
+
+	out := make(chan gqlReply)
+	live, patch := liveOptions(operation, variables)
+	if operation.Operation != ast.Subscription && !live {
+		go func() {
+			defer close(out)
+			select {
+			case out <- my.Execute(ctx, query, variables, operationName):
+			case <-ctx.Done():
+			}
+		}()
+		return out, nil
+	}
+
+	notify, cancel := my.broker.register(snapshot.collectTables(operation.SelectionSet, doc.Fragments))
+	// 收到变更通知后立即重新执行，读主库避免副本延迟导致推送旧结果
+	ctx = std.UsePrimary(ctx)
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		var last []byte
+		var prev interface{}
+		for {
+			// 每次都在当前快照上执行，热加载后自动使用新的schema
+			r := my.Execute(ctx, query, variables, operationName)
+			data, _ := json.Marshal(r)
+			if !bytes.Equal(data, last) {
+				if patch {
+					var doc interface{}
+					_ = json.Unmarshal(data, &doc)
+					if last != nil {
+						r = gqlReply{Patch: diffPatch(prev, doc, "")}
+					}
+					prev = doc
+				}
+				last = data
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-notify:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// collectTables 收集选择集涉及的表，包括关联查询和片段中的表
+func (my *Executor) collectTables(set ast.SelectionSet, fragments ast.FragmentDefinitionList) map[string]bool {
+	tables := make(map[string]bool)
+	var walk func(ast.SelectionSet)
+	walk = func(set ast.SelectionSet) {
+		for _, s := range set {
+			switch v := s.(type) {
+			case *ast.Field:
+				if v.Definition != nil {
+					if class, ok := my.metadata.Nodes[v.Definition.Type.Name()]; ok && !class.Virtual && class.Table != "" {
+						tables[class.Table] = true
+					}
+				}
+				walk(v.SelectionSet)
+			case *ast.InlineFragment:
+				walk(v.SelectionSet)
+			case *ast.FragmentSpread:
+				if def := fragments.ForName(v.Name); def != nil {
+					walk(def.SelectionSet)
+				}
+			}
+		}
+	}
+	walk(set)
+	return tables
+}
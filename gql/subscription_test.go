@@ -0,0 +1,149 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/event"
+	_ "github.com/ichaly/ideabase/std/event/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestSubscriptionTables(t *testing.T) {
+	executor := newMockExecutor(t, createMockMetadata(t))
+
+	doc, err := gqlparser.LoadQuery(executor.schema, `
+		subscription { posts { items { ...PostFields } } }
+		fragment PostFields on Post { id title }
+	`)
+	require.Nil(t, err)
+	tables := executor.collectTables(doc.Operations[0].SelectionSet, doc.Fragments)
+	assert.Equal(t, map[string]bool{"posts": true}, tables)
+}
+
+func TestSubscriptionBroker(t *testing.T) {
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	b := newBroker(bus)
+
+	users, cancelUsers := b.register(map[string]bool{"users": true})
+	posts, cancelPosts := b.register(map[string]bool{"posts": true})
+	defer cancelPosts()
+
+	ctx := context.Background()
+	require.NoError(t, event.Publish(ctx, bus, event.TopicTableChange, event.TableChange{Table: "users", Action: event.ActionUpdate}))
+	require.NoError(t, event.Publish(ctx, bus, event.TopicTableChange, event.TableChange{Table: "users", Action: event.ActionCreate}))
+
+	// 多次变更合并为一次通知，无关的订阅不受影响
+	assert.Len(t, users, 1)
+	assert.Len(t, posts, 0)
+
+	// 取消后不再接收通知
+	<-users
+	cancelUsers()
+	require.NoError(t, event.Publish(ctx, bus, event.TopicTableChange, event.TableChange{Table: "users"}))
+	assert.Len(t, users, 0)
+}
+
+func TestSubscriptionWebSocket(t *testing.T) {
+	meta := createMockMetadata(t)
+	meta.cfg.Executor.Subscription.InitTimeout = time.Second
+	meta.cfg.Root = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(meta.cfg.Root, "cfg"), 0755))
+	executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil,
+		WithAuthenticator(func(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+			if payload["token"] != "secret" {
+				return nil, errors.New("invalid token")
+			}
+			return ctx, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	executor.Bind(app.Group(executor.Path()))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	defer func() { _ = app.Shutdown() }()
+
+	url := "ws://" + ln.Addr().String() + executor.Path()
+	dial := func(t *testing.T) *websocket.Conn {
+		dialer := websocket.Dialer{Subprotocols: []string{wsSubprotocol}}
+		conn, _, err := dialer.Dial(url, nil)
+		require.NoError(t, err)
+		require.Equal(t, wsSubprotocol, conn.Subprotocol())
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		return conn
+	}
+	write := func(t *testing.T, conn *websocket.Conn, msg string) {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+	}
+	read := func(t *testing.T, conn *websocket.Conn, msg *wsMessage) {
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, msg))
+	}
+	closeCode := func(conn *websocket.Conn) int {
+		_, _, err := conn.ReadMessage()
+		var ce *websocket.CloseError
+		if errors.As(err, &ce) {
+			return ce.Code
+		}
+		return 0
+	}
+
+	t.Run("鉴权失败", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+		write(t, conn, `{"type":"connection_init","payload":{"token":"bad"}}`)
+		assert.Equal(t, wsCloseForbidden, closeCode(conn))
+	})
+
+	t.Run("未初始化即订阅", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { users { total } }"}}`)
+		assert.Equal(t, wsCloseUnauthorized, closeCode(conn))
+	})
+
+	t.Run("初始化超时", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+		assert.Equal(t, wsCloseInitTimeout, closeCode(conn))
+	})
+
+	t.Run("协议流程", func(t *testing.T) {
+		conn := dial(t)
+		defer conn.Close()
+
+		var msg wsMessage
+		write(t, conn, `{"type":"connection_init","payload":{"token":"secret"}}`)
+		read(t, conn, &msg)
+		assert.Equal(t, wsConnectionAck, msg.Type)
+
+		write(t, conn, `{"type":"ping"}`)
+		read(t, conn, &msg)
+		assert.Equal(t, wsPong, msg.Type)
+
+		// 查询校验失败时返回error消息
+		write(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { unknown }"}}`)
+		read(t, conn, &msg)
+		assert.Equal(t, wsError, msg.Type)
+		assert.Equal(t, "1", msg.Id)
+		assert.Contains(t, string(msg.Payload), "unknown")
+
+		// 重复初始化关闭连接
+		write(t, conn, `{"type":"connection_init"}`)
+		assert.Equal(t, wsCloseTooManyInitRequest, closeCode(conn))
+	})
+}
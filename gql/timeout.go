@@ -0,0 +1,82 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ichaly/ideabase/std"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// 取消和超时错误码，写入错误的extensions.code
+const (
+	CODE_REQUEST_CANCELLED = "REQUEST_CANCELLED"
+	CODE_REQUEST_TIMEOUT   = "REQUEST_TIMEOUT"
+)
+
+// cancelCode 识别请求取消和执行超时，包括数据库语句超时，其他错误返回空字符串
+func cancelCode(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CODE_REQUEST_CANCELLED
+	case errors.Is(err, context.DeadlineExceeded):
+		return CODE_REQUEST_TIMEOUT
+	}
+	// PostgreSQL的statement_timeout报告为query_canceled
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "57014" {
+		return CODE_REQUEST_TIMEOUT
+	}
+	// MySQL的MAX_EXECUTION_TIME报告为ER_QUERY_TIMEOUT
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == 3024 {
+		return CODE_REQUEST_TIMEOUT
+	}
+	return ""
+}
+
+// withTimeout 按配置为单个操作设置执行期限，0表示只受请求上下文控制
+func (my *Executor) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := my.metadata.cfg.Executor.Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// scan 在请求上下文中执行SQL，配置了超时时同时设置数据库的语句超时
+// 客户端断开或超过期限时驱动中止查询，数据库侧的超时在连接池异常时兜底
+func (my *Executor) scan(ctx context.Context, sql string, args []any, result *map[string]interface{}) error {
+	db := my.database.WithContext(ctx)
+	timeout := my.metadata.cfg.Executor.Timeout
+	if timeout <= 0 || my.compiler == nil {
+		return db.Raw(sql, args...).Scan(result).Error
+	}
+	switch my.compiler.dialect.Name() {
+	case "postgresql":
+		// SET LOCAL只在当前事务内生效，不会影响连接池中的其他连接；查询的事务按上下文开启在只读副本上
+		return std.RouteTransaction(db).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())).Error; err != nil {
+				return err
+			}
+			return tx.Raw(sql, args...).Scan(result).Error
+		})
+	case "mysql":
+		sql = maxExecutionTime(sql, timeout)
+	}
+	return db.Raw(sql, args...).Scan(result).Error
+}
+
+// maxExecutionTime 为MySQL的SELECT语句添加MAX_EXECUTION_TIME优化器提示
+// 提示只对只读的SELECT生效，其他语句原样返回
+func maxExecutionTime(sql string, timeout time.Duration) string {
+	const keyword = "SELECT"
+	if len(sql) < len(keyword) || !strings.EqualFold(sql[:len(keyword)], keyword) {
+		return sql
+	}
+	return fmt.Sprintf("%s /*+ MAX_EXECUTION_TIME(%d) */%s", sql[:len(keyword)], timeout.Milliseconds(), sql[len(keyword):])
+}
@@ -0,0 +1,75 @@
+package gql
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestTimeoutExecution(t *testing.T) {
+	var calls atomic.Int32
+	executor := newCountingExecutor(t)
+	db, connector := newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		calls.Add(1)
+		if query == `SELECT "users"` {
+			return `{"users":{"total":1}}`, nil
+		}
+		// 模拟慢查询，直到上下文结束
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	executor.database = db
+
+	t.Run("取消的请求", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r := executor.Execute(ctx, `{ users { total } }`, nil, "")
+		assert.Equal(t, map[string]interface{}{"users": nil}, r.Data)
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, ast.Path{ast.PathName("users")}, r.Errors[0].Path)
+		assert.Equal(t, CODE_REQUEST_CANCELLED, r.Errors[0].Extensions["code"])
+	})
+
+	t.Run("超时后不再逐个重试根字段", func(t *testing.T) {
+		executor.metadata.cfg.Executor.Timeout = 50 * time.Millisecond
+		calls.Store(0)
+		r := executor.Execute(context.Background(), `{ posts { total } users { total } }`, nil, "")
+		assert.Equal(t, map[string]interface{}{"posts": nil, "users": nil}, r.Data)
+		require.Len(t, r.Errors, 2)
+		for _, e := range r.Errors {
+			assert.Equal(t, CODE_REQUEST_TIMEOUT, e.Extensions["code"])
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("PostgreSQL在事务中设置语句超时", func(t *testing.T) {
+		executor.metadata.cfg.Executor.Timeout = 2 * time.Second
+		executor.compiler.dialect = &countingDialect{name: "postgresql"}
+		r := executor.Execute(context.Background(), `{ users { total } }`, nil, "")
+		require.Empty(t, r.Errors)
+		assert.Equal(t, map[string]interface{}{"total": float64(1)}, r.Data["users"])
+		assert.Equal(t, []string{"SET LOCAL statement_timeout = 2000"}, connector.execs)
+	})
+}
+
+func TestTimeoutCode(t *testing.T) {
+	assert.Equal(t, CODE_REQUEST_CANCELLED, cancelCode(context.Canceled))
+	assert.Equal(t, CODE_REQUEST_TIMEOUT, cancelCode(context.DeadlineExceeded))
+	assert.Equal(t, CODE_REQUEST_TIMEOUT, cancelCode(&pgconn.PgError{Code: "57014"}))
+	assert.Equal(t, CODE_REQUEST_TIMEOUT, cancelCode(&mysql.MySQLError{Number: 3024}))
+	assert.Empty(t, cancelCode(&pgconn.PgError{Code: "23505"}))
+}
+
+func TestTimeoutMaxExecutionTime(t *testing.T) {
+	timeout := 1500 * time.Millisecond
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(1500) */ 1", maxExecutionTime("SELECT 1", timeout))
+	assert.Equal(t, "select /*+ MAX_EXECUTION_TIME(1500) */ 1", maxExecutionTime("select 1", timeout))
+	assert.Equal(t, "INSERT INTO users VALUES (1)", maxExecutionTime("INSERT INTO users VALUES (1)", timeout), "只对SELECT生效")
+}
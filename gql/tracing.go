@@ -0,0 +1,213 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 执行计划指令相关常量
+const (
+	DIRECTIVE_EXPLAIN = "explain"
+
+	DESC_EXPLAIN = "返回编译后SQL的执行计划，不执行操作，需开启执行追踪"
+)
+
+// SPAN_OPERATION 链路追踪中GraphQL操作span的默认名称，解析出操作后改为操作类型和名称
+const SPAN_OPERATION = "GraphQL Operation"
+
+// tracer GraphQL操作的链路追踪器，未启用OpenTelemetry时全局提供者为空实现
+var tracer = otel.Tracer("github.com/ichaly/ideabase/gql")
+
+// 执行追踪记录的阶段
+const (
+	phaseParse = iota
+	phaseValidate
+	phaseCompile
+	phaseExecute
+)
+
+// phaseNames 阶段在extensions.tracing中的名称
+var phaseNames = [...]string{"parse", "validate", "compile", "execute"}
+
+// traceKey 执行追踪在上下文中的键
+type traceKey struct{}
+
+// traceQuery 执行的SQL和参数，仅开发模式下记录
+type traceQuery struct {
+	Sql  string `json:"sql"`
+	Args []any  `json:"args"`
+}
+
+// tracing 单次请求的执行追踪，记录解析、校验、编译和执行阶段的耗时
+// 根字段逐个重试或Relay节点查询会多次编译执行，耗时累加
+type tracing struct {
+	mu      sync.Mutex
+	debug   bool
+	start   time.Time
+	phases  [len(phaseNames)]time.Duration
+	queries []traceQuery
+}
+
+// withTracing 开启执行追踪时在上下文中挂载追踪记录，未开启时原样返回
+func (my *Executor) withTracing(ctx context.Context) (context.Context, *tracing) {
+	if !my.metadata.cfg.Executor.Tracing.Enable {
+		return ctx, nil
+	}
+	t := &tracing{debug: my.metadata.cfg.IsDebug(), start: time.Now()}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// traceFrom 返回上下文中的追踪记录，未开启时为空，空记录上的方法不做任何事
+func traceFrom(ctx context.Context) *tracing {
+	t, _ := ctx.Value(traceKey{}).(*tracing)
+	return t
+}
+
+// since 将从start开始的耗时累加到阶段
+func (my *tracing) since(phase int, start time.Time) {
+	if my == nil {
+		return
+	}
+	my.mu.Lock()
+	my.phases[phase] += time.Since(start)
+	my.mu.Unlock()
+}
+
+// record 记录执行的SQL和参数，只在开发模式下生效
+func (my *tracing) record(sql string, args []any) {
+	if my == nil || !my.debug {
+		return
+	}
+	my.mu.Lock()
+	my.queries = append(my.queries, traceQuery{Sql: sql, Args: args})
+	my.mu.Unlock()
+}
+
+// extension 生成响应中的extensions.tracing，耗时单位为纳秒
+func (my *tracing) extension() map[string]interface{} {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	end := time.Now()
+	ext := map[string]interface{}{
+		"startTime": my.start.Format(time.RFC3339Nano),
+		"endTime":   end.Format(time.RFC3339Nano),
+		"duration":  end.Sub(my.start).Nanoseconds(),
+	}
+	for i, name := range phaseNames {
+		ext[name] = my.phases[i].Nanoseconds()
+	}
+	if my.queries != nil {
+		ext["queries"] = my.queries
+	}
+	return ext
+}
+
+// renderExplain 渲染@explain指令
+func (my *Renderer) renderExplain() error {
+	my.writeLine("# ", DESC_EXPLAIN)
+	my.writeLine("directive @", DIRECTIVE_EXPLAIN, " on QUERY | MUTATION")
+	my.writeLine()
+	return nil
+}
+
+// explain 编译操作的数据字段并返回数据库的执行计划，操作本身不执行
+// PostgreSQL使用EXPLAIN (FORMAT JSON)，MySQL使用EXPLAIN FORMAT=JSON
+func (my *Executor) explain(ctx context.Context, entry *planEntry, variables map[string]interface{}) gqlReply {
+	var r gqlReply
+	if !my.metadata.cfg.Executor.Tracing.Enable {
+		r.Errors = gqlerror.List{gqlerror.Errorf("未开启执行追踪，不支持@%s指令", DIRECTIVE_EXPLAIN)}
+		return r
+	}
+	if entry.data == nil {
+		r.Errors = gqlerror.List{gqlerror.Errorf("@%s指令需要至少一个数据字段", DIRECTIVE_EXPLAIN)}
+		return r
+	}
+
+	prefix := ""
+	switch name := my.compiler.dialect.Name(); name {
+	case "postgresql":
+		prefix = "EXPLAIN (FORMAT JSON) "
+	case "mysql":
+		prefix = "EXPLAIN FORMAT=JSON "
+	default:
+		r.Errors = gqlerror.List{gqlerror.Errorf("方言%s不支持@%s指令", name, DIRECTIVE_EXPLAIN)}
+		return r
+	}
+
+	t := traceFrom(ctx)
+	start := time.Now()
+	sql, args, err := my.build(entry, entry.data, my.decodeNodeArgs(entry.data, variables))
+	t.since(phaseCompile, start)
+	if err == nil {
+		t.record(sql, args)
+		start = time.Now()
+		var plan interface{}
+		if plan, err = my.queryPlan(ctx, prefix+sql, args); err == nil {
+			t.since(phaseExecute, start)
+			r.Extensions = map[string]interface{}{DIRECTIVE_EXPLAIN: plan}
+			return r
+		}
+	}
+	for _, field := range rootFields(entry.data.SelectionSet) {
+		r.Errors = append(r.Errors, my.fieldError(field, err))
+	}
+	return r
+}
+
+// queryPlan 执行EXPLAIN语句，解析结果中唯一一列的JSON执行计划
+func (my *Executor) queryPlan(ctx context.Context, sql string, args []any) (interface{}, error) {
+	result := make(map[string]interface{})
+	if err := my.database.WithContext(ctx).Raw(sql, args...).Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("无法解析的执行计划: %v", result)
+	}
+	for _, v := range result {
+		var data []byte
+		switch val := v.(type) {
+		case string:
+			data = []byte(val)
+		case []byte:
+			data = val
+		default:
+			return v, nil
+		}
+		var plan interface{}
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return nil, err
+		}
+		return plan, nil
+	}
+	return nil, nil
+}
+
+// traceOperation 将操作类型和名称记录到链路追踪span，匿名操作只使用操作类型命名
+func traceOperation(ctx context.Context, operation *ast.OperationDefinition) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	name := string(operation.Operation)
+	if operation.Name != "" {
+		name += " " + operation.Name
+	}
+	span.SetName(name)
+	span.SetAttributes(
+		attribute.String("graphql.operation.type", string(operation.Operation)),
+		attribute.String("graphql.operation.name", operation.Name),
+	)
+}
+
+// explainOperation 判断操作是否声明了@explain指令
+func explainOperation(operation *ast.OperationDefinition) bool {
+	return operation.Directives.ForName(DIRECTIVE_EXPLAIN) != nil
+}
@@ -0,0 +1,141 @@
+package gql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ichaly/ideabase/std"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingExtension(t *testing.T) {
+	executor := newCountingExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		return `{"users":{"total":1}}`, nil
+	})
+	executor.metadata.cfg.Mode = "dev"
+	executor.plans = newPlanCache(10)
+	query := `query ($id: ID) { users(id: $id) { total } }`
+
+	t.Run("未开启时不返回追踪信息", func(t *testing.T) {
+		r := executor.Execute(context.Background(), query, map[string]interface{}{"id": 1}, "")
+		require.Empty(t, r.Errors)
+		assert.Nil(t, r.Extensions)
+	})
+
+	executor.metadata.cfg.Executor.Tracing.Enable = true
+	t.Run("返回各阶段耗时和执行的SQL", func(t *testing.T) {
+		r := executor.Execute(context.Background(), query, map[string]interface{}{"id": 1}, "")
+		require.Empty(t, r.Errors)
+		tracing, ok := r.Extensions["tracing"].(map[string]interface{})
+		require.True(t, ok)
+		for _, name := range phaseNames {
+			assert.Contains(t, tracing, name)
+		}
+		assert.Positive(t, tracing["duration"])
+		assert.Equal(t, []traceQuery{{Sql: `SELECT "users" WHERE id = $1`, Args: []any{1}}}, tracing["queries"])
+	})
+
+	t.Run("缓存的计划不再解析和校验", func(t *testing.T) {
+		r := executor.Execute(context.Background(), query, map[string]interface{}{"id": 2}, "")
+		tracing := r.Extensions["tracing"].(map[string]interface{})
+		assert.Zero(t, tracing["parse"])
+		assert.Zero(t, tracing["validate"])
+		assert.Equal(t, []any{2}, tracing["queries"].([]traceQuery)[0].Args, "按本次请求的变量绑定")
+	})
+
+	t.Run("非开发模式不返回SQL", func(t *testing.T) {
+		executor.metadata.cfg.Mode = "prod"
+		defer func() { executor.metadata.cfg.Mode = "dev" }()
+		r := executor.Execute(context.Background(), query, nil, "")
+		assert.NotContains(t, r.Extensions["tracing"], "queries")
+	})
+}
+
+func TestTracingExplain(t *testing.T) {
+	executor := newCountingExecutor(t)
+	var queries []string
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		queries = append(queries, query)
+		return `[{"Plan":{"Node Type":"Seq Scan"}}]`, nil
+	})
+	query := `query @explain { users { total } }`
+
+	r := executor.Execute(context.Background(), query, nil, "")
+	require.Len(t, r.Errors, 1, "未开启执行追踪时拒绝@explain")
+	assert.Empty(t, queries)
+
+	executor.metadata.cfg.Executor.Tracing.Enable = true
+	r = executor.Execute(context.Background(), query, nil, "")
+	require.Len(t, r.Errors, 1, "不支持的方言")
+
+	executor.compiler.dialect = &countingDialect{name: "postgresql"}
+	r = executor.Execute(context.Background(), query, nil, "")
+	require.Empty(t, r.Errors)
+	assert.Nil(t, r.Data, "只返回执行计划，不执行操作")
+	assert.Equal(t, []interface{}{map[string]interface{}{"Plan": map[string]interface{}{"Node Type": "Seq Scan"}}}, r.Extensions[DIRECTIVE_EXPLAIN])
+	assert.Equal(t, []string{`EXPLAIN (FORMAT JSON) SELECT "users"`}, queries)
+	assert.Contains(t, r.Extensions, "tracing")
+}
+
+func TestOperationMetrics(t *testing.T) {
+	executor := newCountingExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		return `{"users":{"total":1}}`, nil
+	})
+	m, err := std.NewMetrics(nil, nil, nil)
+	require.NoError(t, err)
+	executor.metrics = m
+	executor.metadata.cfg.Executor.Metrics.MaxOperations = 1
+	executor.trusted.store(Manifest{"list": `query list { users { total } }`})
+
+	executor.Execute(context.Background(), `query list { users { total } }`, nil, "")
+	executor.Execute(context.Background(), `query list { users { missing } }`, nil, "")
+	executor.Execute(context.Background(), `{ users { total } }`, nil, "")
+	executor.Execute(context.Background(), `query random1 { users { total } }`, nil, "")
+	executor.Execute(context.Background(), `query random2 { users { total } }`, nil, "")
+
+	expected := `
+# HELP graphql_operations_total GraphQL操作数，按操作类型、名称和结果统计
+# TYPE graphql_operations_total counter
+graphql_operations_total{name="",status="ok",type="query"} 1
+graphql_operations_total{name="list",status="ok",type="query"} 1
+graphql_operations_total{name="other",status="ok",type="query"} 1
+graphql_operations_total{name="random1",status="ok",type="query"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "graphql_operations_total"), "校验失败的操作不计入")
+	count, err := testutil.GatherAndCount(m.Registry(), "graphql_operation_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count, "清单中的操作名不占用标签上限")
+}
+
+func TestOperationMetricsWithoutManifest(t *testing.T) {
+	executor := newCountingExecutor(t)
+	executor.database, _ = newFakeDatabase(t, func(ctx context.Context, query string) (string, error) {
+		return `{"users":{"total":1}}`, nil
+	})
+	m, err := std.NewMetrics(nil, nil, nil)
+	require.NoError(t, err)
+	executor.metrics = m
+	executor.metadata.cfg.Executor.Trusted.Enable = false
+	executor.metadata.cfg.Executor.Metrics.Operations = []string{"list"}
+	executor.metadata.cfg.Executor.Metrics.MaxOperations = 2
+
+	for _, name := range []string{"first", "second", "third", "first", "list", "fourth"} {
+		r := executor.Execute(context.Background(), `query `+name+` { users { total } }`, nil, "")
+		require.Empty(t, r.Errors)
+	}
+
+	expected := `
+# HELP graphql_operations_total GraphQL操作数，按操作类型、名称和结果统计
+# TYPE graphql_operations_total counter
+graphql_operations_total{name="first",status="ok",type="query"} 2
+graphql_operations_total{name="list",status="ok",type="query"} 1
+graphql_operations_total{name="other",status="ok",type="query"} 2
+graphql_operations_total{name="second",status="ok",type="query"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "graphql_operations_total"), "配置的操作名始终作为标签，其他操作名达到上限后统计为other")
+}
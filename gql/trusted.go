@@ -0,0 +1,190 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/log"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// 可信文档相关错误码
+const (
+	CODE_TRUSTED_DOCUMENT_REQUIRED  = "TRUSTED_DOCUMENT_REQUIRED"
+	CODE_TRUSTED_DOCUMENT_NOT_FOUND = "TRUSTED_DOCUMENT_NOT_FOUND"
+)
+
+// Manifest 可信文档清单，键为文档ID，值为GraphQL文档
+type Manifest map[string]string
+
+// trustedDocuments 可信文档存储，所有快照共享，热加载时整体替换
+type trustedDocuments struct {
+	manifest  atomic.Pointer[Manifest]
+	documents atomic.Pointer[map[string]bool] // 文档文本集合，用于校验执行的查询
+}
+
+// load 按配置加载清单，未启用且未配置清单文件时清空
+func (my *trustedDocuments) load(cfg *internal.Config) error {
+	path := ResolveManifestPath(cfg)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) && !cfg.Executor.Trusted.Enable {
+			my.store(nil)
+			return nil
+		}
+		return fmt.Errorf("读取可信文档清单失败: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取可信文档清单失败: %w", err)
+	}
+	manifest := make(Manifest)
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("解析可信文档清单失败: %w", err)
+	}
+	my.store(manifest)
+	log.Info().Str("file", path).Int("documents", len(manifest)).Msg("已加载可信文档清单")
+	return nil
+}
+
+func (my *trustedDocuments) store(manifest Manifest) {
+	documents := make(map[string]bool, len(manifest))
+	for _, doc := range manifest {
+		documents[doc] = true
+	}
+	my.manifest.Store(&manifest)
+	my.documents.Store(&documents)
+}
+
+// lookup 按文档ID查找文档
+func (my *trustedDocuments) lookup(id string) (string, bool) {
+	manifest := my.manifest.Load()
+	if manifest == nil {
+		return "", false
+	}
+	doc, ok := (*manifest)[id]
+	return doc, ok
+}
+
+// contains 判断查询文本是否为清单中的文档
+func (my *trustedDocuments) contains(query string) bool {
+	documents := my.documents.Load()
+	return documents != nil && (*documents)[query]
+}
+
+// ResolveManifestPath 解析可信文档清单路径，相对路径基于应用根目录
+func ResolveManifestPath(cfg *internal.Config) string {
+	path := cfg.Executor.Trusted.Manifest
+	if path == "" {
+		path = filepath.Join("cfg", "trusted.json")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.Root, path)
+	}
+	return path
+}
+
+// resolveQuery 解析请求实际要执行的查询文本
+// 携带documentId时从清单读取；白名单模式下拒绝其他请求，否则按自动持久化查询处理
+func (my *Executor) resolveQuery(ctx context.Context, req gqlQuery) (string, *gqlerror.Error) {
+	if req.DocumentId != "" {
+		doc, ok := my.trusted.lookup(req.DocumentId)
+		if !ok {
+			return "", newCodeError(CODE_TRUSTED_DOCUMENT_NOT_FOUND, fmt.Sprintf("未找到可信文档: %s", req.DocumentId))
+		}
+		return doc, nil
+	}
+	if my.current.Load().metadata.cfg.Executor.Trusted.Enable {
+		return "", newCodeError(CODE_TRUSTED_DOCUMENT_REQUIRED, "白名单模式下只接受documentId请求")
+	}
+	return my.resolvePersisted(ctx, req)
+}
+
+// ExtractManifest 从目录中的.graphql/.gql客户端文件提取可信文档清单
+// 每个操作连同其依赖的片段（可跨文件）格式化为一个文档，文档ID为其SHA-256摘要
+func ExtractManifest(dir string) (Manifest, error) {
+	var operations ast.OperationList
+	fragments := make(map[string]*ast.FragmentDefinition)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".graphql" && ext != ".gql") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := parser.ParseQuery(&ast.Source{Name: path, Input: string(data)})
+		if err != nil {
+			return fmt.Errorf("解析%s失败: %w", path, err)
+		}
+		operations = append(operations, doc.Operations...)
+		for _, f := range doc.Fragments {
+			if _, ok := fragments[f.Name]; ok {
+				return fmt.Errorf("片段%s重复定义: %s", f.Name, path)
+			}
+			fragments[f.Name] = f
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := make(Manifest, len(operations))
+	for _, op := range operations {
+		doc := &ast.QueryDocument{Operations: ast.OperationList{op}}
+		if err = collectFragments(op.SelectionSet, fragments, map[string]bool{}, &doc.Fragments); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		formatter.NewFormatter(&buf, formatter.WithIndent("  ")).FormatQueryDocument(doc)
+		sum := sha256.Sum256(buf.Bytes())
+		manifest[hex.EncodeToString(sum[:])] = buf.String()
+	}
+	return manifest, nil
+}
+
+// collectFragments 按引用顺序收集选择集依赖的片段
+func collectFragments(set ast.SelectionSet, all map[string]*ast.FragmentDefinition, seen map[string]bool, out *ast.FragmentDefinitionList) error {
+	for _, s := range set {
+		switch v := s.(type) {
+		case *ast.Field:
+			if err := collectFragments(v.SelectionSet, all, seen, out); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := collectFragments(v.SelectionSet, all, seen, out); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if seen[v.Name] {
+				continue
+			}
+			f, ok := all[v.Name]
+			if !ok {
+				return fmt.Errorf("未定义的片段: %s", v.Name)
+			}
+			seen[v.Name] = true
+			*out = append(*out, f)
+			if err := collectFragments(f.SelectionSet, all, seen, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
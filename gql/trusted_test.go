@@ -0,0 +1,122 @@
+package gql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/gql/internal"
+	"github.com/ichaly/ideabase/gql/metadata"
+	"github.com/ichaly/ideabase/std"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedExtractManifest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "users"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fragments.graphql"), []byte(`
+		fragment UserFields on User { id name ...UserEmail }
+		fragment UserEmail on User { email }
+		fragment Unused on User { id }
+	`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "list.gql"), []byte(`
+		query ListUsers { users { items { ...UserFields } } }
+		mutation RemoveUser($id: ID) { deleteUser(id: $id) }
+	`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(`query { ignored }`), 0644))
+
+	manifest, err := ExtractManifest(dir)
+	require.NoError(t, err)
+	require.Len(t, manifest, 2)
+
+	var list string
+	for id, doc := range manifest {
+		sum := sha256.Sum256([]byte(doc))
+		assert.Equal(t, hex.EncodeToString(sum[:]), id, "文档ID应为文档的SHA-256摘要")
+		if strings.Contains(doc, "ListUsers") {
+			list = doc
+		}
+	}
+	assert.Contains(t, list, "fragment UserFields on User")
+	assert.Contains(t, list, "fragment UserEmail on User", "应包含间接依赖的片段")
+	assert.NotContains(t, list, "Unused")
+
+	// 引用未定义的片段时报错
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.graphql"), []byte(`query { users { ...Missing } }`), 0644))
+	_, err = ExtractManifest(dir)
+	assert.ErrorContains(t, err, "Missing")
+}
+
+func TestTrustedDocuments(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "cfg"), 0755))
+	manifest := filepath.Join(root, "cfg", "trusted.json")
+	query := "{ __schema { queryType { name } } }"
+	writeManifest := func(m Manifest) {
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(manifest, data, 0644))
+	}
+
+	k, err := std.NewKonfig()
+	require.NoError(t, err)
+	k.Set("mode", "test")
+	k.Set("app.root", root)
+	k.Set("executor.trusted.enable", true)
+	k.Set("metadata.classes", map[string]*internal.ClassConfig{
+		"User": {
+			Table:  "users",
+			Fields: map[string]*internal.FieldConfig{"id": {Column: "id", Type: "int", IsPrimary: true}},
+		},
+	})
+	meta, err := NewMetadata(k, nil, WithoutLoader(metadata.LoaderFile))
+	require.NoError(t, err)
+
+	// 白名单模式下清单缺失时拒绝启动
+	_, err = NewExecutor(nil, NewRenderer(meta), meta, nil)
+	require.Error(t, err)
+
+	writeManifest(Manifest{"intro": query})
+	executor, err := NewExecutor(nil, NewRenderer(meta), meta, nil)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	executor.Bind(app.Group(executor.Path()))
+	post := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, executor.Path(), strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	assert.Contains(t, post(`{"documentId":"intro"}`), `"queryType":{"name":"Query"}`)
+	assert.Contains(t, post(`{"documentId":"unknown"}`), CODE_TRUSTED_DOCUMENT_NOT_FOUND)
+	assert.Contains(t, post(`{"query":"`+query+`"}`), CODE_TRUSTED_DOCUMENT_REQUIRED, "白名单模式下拒绝查询文本")
+
+	// Execute只执行清单中的文档
+	assert.Empty(t, executor.Execute(context.Background(), query, nil, "").Errors)
+	r := executor.Execute(context.Background(), "{ __schema { types { name } } }", nil, "")
+	require.Len(t, r.Errors, 1)
+	assert.Equal(t, CODE_TRUSTED_DOCUMENT_REQUIRED, r.Errors[0].Extensions["code"])
+
+	// 热加载后使用新的清单，加载失败时保留当前清单
+	writeManifest(Manifest{"intro-v2": query})
+	require.NoError(t, executor.Reload())
+	assert.Contains(t, post(`{"documentId":"intro-v2"}`), `"queryType":{"name":"Query"}`)
+	assert.Contains(t, post(`{"documentId":"intro"}`), CODE_TRUSTED_DOCUMENT_NOT_FOUND)
+
+	require.NoError(t, os.WriteFile(manifest, []byte("{"), 0644))
+	require.NoError(t, executor.Reload())
+	assert.Contains(t, post(`{"documentId":"intro-v2"}`), `"queryType":{"name":"Query"}`)
+}
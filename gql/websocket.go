@@ -0,0 +1,314 @@
+package gql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/log"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// graphql-transport-ws协议常量
+// 参考: https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	wsSubprotocol = "graphql-transport-ws"
+
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// graphql-transport-ws协议关闭码
+const (
+	wsCloseInvalidMessage     = 4400
+	wsCloseUnauthorized       = 4401
+	wsCloseForbidden          = 4403
+	wsCloseSubprotocol        = 4406
+	wsCloseInitTimeout        = 4408
+	wsCloseSubscriberExists   = 4409
+	wsCloseTooManyInitRequest = 4429
+)
+
+// Authenticator WebSocket连接鉴权函数
+// 接收connection_init的payload，返回携带用户信息的上下文，返回错误时以4403关闭连接
+type Authenticator func(ctx context.Context, payload map[string]interface{}) (context.Context, error)
+
+// wsMessage graphql-transport-ws协议消息
+type wsMessage struct {
+	Id      string              `json:"id,omitempty"`
+	Type    string              `json:"type"`
+	Payload jsoniter.RawMessage `json:"payload,omitempty"`
+}
+
+// WebSocket 处理graphql-transport-ws协议的WebSocket连接
+// 鉴权通过connection_init的payload完成而不依赖Cookie，因此不限制跨域来源
+func (my *Executor) WebSocket(c fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.RequestCtx()) {
+		return fiber.ErrUpgradeRequired
+	}
+	// 连接在请求结束后继续存在，只保留请求上下文中的值
+	base := context.WithoutCancel(c.Context())
+	upgrader := websocket.FastHTTPUpgrader{
+		Subprotocols: []string{wsSubprotocol},
+		CheckOrigin:  func(*fasthttp.RequestCtx) bool { return true },
+	}
+	return upgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+		newWsConnection(my, conn, base).serve()
+	})
+}
+
+// wsConnection 单个WebSocket连接
+type wsConnection struct {
+	executor   *Executor
+	conn       *websocket.Conn
+	root       context.Context // 连接上下文，连接关闭时取消
+	ctx        context.Context // 鉴权后的连接上下文，派生自root
+	cancel     context.CancelFunc
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+	inited     bool
+	acked      bool
+	operations map[string]context.CancelFunc
+}
+
+func newWsConnection(e *Executor, conn *websocket.Conn, base context.Context) *wsConnection {
+	ctx, cancel := context.WithCancel(base)
+	return &wsConnection{
+		executor:   e,
+		conn:       conn,
+		root:       ctx,
+		ctx:        ctx,
+		cancel:     cancel,
+		operations: make(map[string]context.CancelFunc),
+	}
+}
+
+// serve 读取并处理客户端消息，直到连接关闭
+func (my *wsConnection) serve() {
+	defer my.close()
+
+	if my.conn.Subprotocol() != wsSubprotocol {
+		my.closeWith(wsCloseSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+
+	cfg := my.executor.current.Load().metadata.cfg.Executor.Subscription
+	if cfg.InitTimeout > 0 {
+		timer := time.AfterFunc(cfg.InitTimeout, func() {
+			my.mu.Lock()
+			acked := my.acked
+			my.mu.Unlock()
+			if !acked {
+				my.closeWith(wsCloseInitTimeout, "Connection initialisation timeout")
+			}
+		})
+		defer timer.Stop()
+	}
+	if cfg.KeepAlive > 0 {
+		go my.keepAlive(cfg.KeepAlive)
+	}
+
+	for {
+		_, data, err := my.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if err = json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			my.closeWith(wsCloseInvalidMessage, "Invalid message received")
+			return
+		}
+		if !my.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle 处理单条消息，返回false表示连接已关闭
+func (my *wsConnection) handle(msg wsMessage) bool {
+	switch msg.Type {
+	case wsConnectionInit:
+		return my.handleInit(msg.Payload)
+	case wsPing:
+		my.send(wsMessage{Type: wsPong})
+	case wsPong:
+	case wsSubscribe:
+		return my.handleSubscribe(msg)
+	case wsComplete:
+		my.finish(msg.Id)
+	default:
+		my.closeWith(wsCloseInvalidMessage, "Invalid message received")
+		return false
+	}
+	return true
+}
+
+// handleInit 处理connection_init，完成鉴权后回复connection_ack
+func (my *wsConnection) handleInit(raw jsoniter.RawMessage) bool {
+	my.mu.Lock()
+	inited := my.inited
+	my.inited = true
+	my.mu.Unlock()
+	if inited {
+		my.closeWith(wsCloseTooManyInitRequest, "Too many initialisation requests")
+		return false
+	}
+
+	payload := make(map[string]interface{})
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &payload)
+	}
+	ctx := my.ctx
+	if auth := my.executor.auth; auth != nil {
+		var err error
+		if ctx, err = auth(my.ctx, payload); err != nil {
+			log.Debug().Err(err).Msg("WebSocket连接鉴权失败")
+			my.closeWith(wsCloseForbidden, "Forbidden")
+			return false
+		}
+	}
+
+	my.mu.Lock()
+	my.ctx = ctx
+	my.acked = true
+	my.mu.Unlock()
+	my.send(wsMessage{Type: wsConnectionAck})
+	return true
+}
+
+// handleSubscribe 处理subscribe，每个操作在独立的协程中执行
+func (my *wsConnection) handleSubscribe(msg wsMessage) bool {
+	var req gqlQuery
+	if msg.Id == "" || json.Unmarshal(msg.Payload, &req) != nil {
+		my.closeWith(wsCloseInvalidMessage, "Invalid message received")
+		return false
+	}
+
+	my.mu.Lock()
+	if !my.acked {
+		my.mu.Unlock()
+		my.closeWith(wsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, ok := my.operations[msg.Id]; ok {
+		my.mu.Unlock()
+		my.closeWith(wsCloseSubscriberExists, "Subscriber for "+msg.Id+" already exists")
+		return false
+	}
+	ctx, cancel := context.WithCancel(my.ctx)
+	my.operations[msg.Id] = cancel
+	my.mu.Unlock()
+
+	go my.run(ctx, msg.Id, req)
+	return true
+}
+
+// run 执行操作并推送结果，结果通道关闭后回复complete
+func (my *wsConnection) run(ctx context.Context, id string, req gqlQuery) {
+	query, gErr := my.executor.resolveQuery(ctx, req)
+	if gErr != nil {
+		if my.remove(id) {
+			my.send(wsMessage{Id: id, Type: wsError, Payload: mustMarshal(gqlerror.List{gErr})})
+		}
+		return
+	}
+	ch, err := my.executor.Subscribe(ctx, query, req.Variables, req.OperationName)
+	if err != nil {
+		errs, ok := err.(gqlerror.List)
+		if !ok {
+			errs = gqlerror.List{gqlerror.Wrap(err)}
+		}
+		if my.remove(id) {
+			my.send(wsMessage{Id: id, Type: wsError, Payload: mustMarshal(errs)})
+		}
+		return
+	}
+	for r := range ch {
+		my.send(wsMessage{Id: id, Type: wsNext, Payload: mustMarshal(r)})
+	}
+	// 客户端主动取消时不再回复complete
+	if my.remove(id) {
+		my.send(wsMessage{Id: id, Type: wsComplete})
+	}
+}
+
+// finish 客户端取消订阅
+func (my *wsConnection) finish(id string) {
+	my.mu.Lock()
+	cancel, ok := my.operations[id]
+	delete(my.operations, id)
+	my.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// remove 移除操作，返回操作是否仍在注册表中
+func (my *wsConnection) remove(id string) bool {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	cancel, ok := my.operations[id]
+	if ok {
+		cancel()
+		delete(my.operations, id)
+	}
+	return ok
+}
+
+// keepAlive 定时发送ping保持连接
+func (my *wsConnection) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	done := my.root.Done()
+	for {
+		select {
+		case <-ticker.C:
+			if err := my.send(wsMessage{Type: wsPing}); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// send 串行写入消息
+func (my *wsConnection) send(msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	my.writeMu.Lock()
+	defer my.writeMu.Unlock()
+	return my.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// closeWith 按协议关闭码关闭连接
+func (my *wsConnection) closeWith(code int, reason string) {
+	my.writeMu.Lock()
+	_ = my.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	my.writeMu.Unlock()
+	my.close()
+}
+
+// close 取消所有操作并关闭连接
+func (my *wsConnection) close() {
+	my.cancel()
+	_ = my.conn.Close()
+}
+
+// mustMarshal 序列化消息载荷，结果类型均可序列化
+func mustMarshal(v interface{}) jsoniter.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -12,12 +12,31 @@ import (
 
 var (
 	_ = Bind(newAdapter)
+	_ = Invoke(std.InitTracing)
 	_ = Bind(std.NewFiber)
-	_ = Bind(std.NewHealth, As[std.Plugin](), Out("plugin"))
+	_ = Bind(std.NewHealth, In("", "health"))
+	_ = Bind(healthPlugin, Out("plugin"))
+	_ = Bind(std.NewMetrics, In(`optional:"true"`, `optional:"true"`, `optional:"true"`))
+	_ = Bind(metricsPlugin, Out("plugin"))
+	_ = Bind((*std.Metrics).Filter, Out("filter"))
+	_ = Bind(std.NewShutdown, In("", "", `optional:"true"`, `optional:"true"`, `optional:"true"`, `optional:"true"`, `optional:"true"`))
 	_ = Invoke(std.Bootstrap, In("plugin", "filter"))
 )
 
-var options []fx.Option
+var (
+	options  []fx.Option
+	entities []func() any
+)
+
+// healthPlugin 将健康检查加入插件分组，关闭流程需要注入健康检查本身以摘除流量
+func healthPlugin(h *std.Health) std.Plugin {
+	return h
+}
+
+// metricsPlugin 将监控指标加入插件分组，指标实例本身仍可注入执行器等组件
+func metricsPlugin(m *std.Metrics) std.Plugin {
+	return m
+}
 
 type bootOption struct {
 	opts []fx.Option
@@ -94,10 +113,20 @@ func Entity[T any](factory ...func() T) struct{} {
 			return v
 		}
 	}
+	entities = append(entities, ctor)
 	options = append(options, fx.Provide(fx.Annotated{Group: "entity", Target: ctor}))
 	return struct{}{}
 }
 
+// Entities 返回通过 Entity 注册的实体，供不启动容器的命令行工具使用。
+func Entities() []any {
+	result := make([]any, len(entities))
+	for i, ctor := range entities {
+		result[i] = ctor()
+	}
+	return result
+}
+
 // As 结果转换为接口类型。
 func As[T any]() BindOption {
 	return func(o *bindOption) {
@@ -4,6 +4,7 @@ import (
 	"github.com/ichaly/ideabase/std"
 	"github.com/ichaly/ideabase/std/cache"
 	"github.com/ichaly/ideabase/std/event"
+	"gorm.io/gorm"
 )
 
 var (
@@ -14,5 +15,18 @@ var (
 	_ = Bind(std.NewGormCache, Out("gorm"))
 	_ = Bind(std.NewSonyFlake, Out("gorm"))
 	_ = Bind(std.NewAudited, Out("gorm"))
+	_ = Bind(std.NewGormTracing, Out("gorm"))
+	_ = Bind(std.NewGormNotify)
+	_ = Bind(gormNotify, Out("gorm"))
+	_ = Invoke((*std.GormNotify).Attach, In("", `optional:"true"`))
 	_ = Bind(std.NewDatabase, In("entity", "gorm"))
+	_ = Bind(std.NewDatabaseChecker, In(`optional:"true"`), Out("health"))
+	_ = Bind(std.NewRedisChecker, In(`optional:"true"`), Out("health"))
+	_ = Bind(std.NewNatsChecker, In(`optional:"true"`), Out("health"))
+	_ = Bind(std.NewEventChecker, In(`optional:"true"`), Out("health"))
 )
+
+// gormNotify 将表变更广播插件加入 gorm 插件分组，事件总线依赖数据库，需创建后再由 Attach 注入
+func gormNotify(n *std.GormNotify) gorm.Plugin {
+	return n
+}
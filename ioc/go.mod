@@ -6,6 +6,7 @@ require (
 	github.com/ichaly/ideabase/gql v0.0.0-20260110145933-e564f1aca14f
 	github.com/ichaly/ideabase/std v0.0.0-20260407145400-53ffe9d8ad6c
 	go.uber.org/fx v1.24.0
+	gorm.io/gorm v1.31.1
 )
 
 require (
@@ -72,5 +73,4 @@ require (
 	golang.org/x/text v0.35.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
-	gorm.io/gorm v1.31.1 // indirect
 )
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"io"
+
 	"github.com/rs/zerolog"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -25,8 +27,8 @@ type Rotate struct {
 	LevelFunc LevelFunc
 }
 
-// NewRotateLogger 创建一个新的日志轮转器
-func NewRotateLogger(r *Rotate) zerolog.Logger {
+// NewRotateLogger 创建一个新的日志轮转器，同时返回日志文件，关闭时由调用方释放
+func NewRotateLogger(r *Rotate) (zerolog.Logger, io.Closer) {
 	w := &lumberjack.Logger{
 		Filename:   r.Filename,
 		MaxSize:    r.MaxSize,
@@ -43,7 +45,7 @@ func NewRotateLogger(r *Rotate) zerolog.Logger {
 		l = zerolog.New(w).With().Timestamp().Logger()
 	}
 
-	return l
+	return l, w
 }
 
 // DefaultRotateConfig 返回默认的日志轮转配置
@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -34,7 +35,8 @@ const (
 
 // Logger 日志记录器
 type Logger struct {
-	l zerolog.Logger
+	l      zerolog.Logger
+	closer io.Closer // 日志文件，控制台输出时为空
 }
 
 // NewLogger 创建新的日志记录器
@@ -81,6 +83,14 @@ func NewLogger(ops ...LoggerOption) *Logger {
 	return &Logger{l: l}
 }
 
+// Close 关闭日志文件，应用退出前调用，之后的写入会重新打开文件
+func (my *Logger) Close() error {
+	if my.closer == nil {
+		return nil
+	}
+	return my.closer.Close()
+}
+
 // SetLevel 设置日志级别
 func (my *Logger) SetLevel(level Level) {
 	my.l = my.l.Level(level)
@@ -138,6 +148,9 @@ func SetDefault(l *Logger) { std = l }
 // SetLevel 设置默认logger的日志级别
 func SetLevel(level Level) { std.SetLevel(level) }
 
+// Close 关闭全局日志记录器的日志文件
+func Close() error { return std.Close() }
+
 // 全局方法
 func Trace() *zerolog.Event { return std.Trace() }
 func Debug() *zerolog.Event { return std.Debug() }
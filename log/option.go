@@ -89,5 +89,6 @@ func NewRotateLogger(ops ...RotateOption) *Logger {
 	for _, o := range ops {
 		o(r)
 	}
-	return &Logger{l: internal.NewRotateLogger(r)}
+	l, w := internal.NewRotateLogger(r)
+	return &Logger{l: l, closer: w}
 }
@@ -35,6 +35,7 @@ func (my *BaseService) DB(ctx context.Context) *gorm.DB {
 }
 
 // WithTransaction 在事务中执行 fn；fn 拿到的 ctx 已携带事务句柄，下游 DB(ctx) 会自动沿用。
+// 事务中的表变更在提交成功后才广播，回滚时丢弃。
 func (my *BaseService) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
 	if my == nil || my.root == nil {
 		return errors.New("基础服务未初始化")
@@ -42,7 +43,12 @@ func (my *BaseService) WithTransaction(ctx context.Context, fn func(context.Cont
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return my.root.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	ctx, changes := withPendingChanges(ctx)
+	err := my.root.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		return fn(context.WithValue(ctx, txKey{}, tx))
 	})
+	if err == nil {
+		changes.flush()
+	}
+	return err
 }
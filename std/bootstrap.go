@@ -33,8 +33,8 @@ type Plugin interface {
 	Bind(fiber.Router)
 }
 
-// Bootstrap 应用程序启动引导函数
-func Bootstrap(p []Plugin, f []Plugin, l Lifecycle, c *Config, a *fiber.App) {
+// Bootstrap 应用程序启动引导函数，应用停止时按s的顺序优雅关闭，s为空时只关闭HTTP服务
+func Bootstrap(p []Plugin, f []Plugin, l Lifecycle, c *Config, a *fiber.App, s *Shutdown) {
 	if BuildTime == "" {
 		BuildTime = time.Now().Format("2006-01-02T15:04:05")
 	}
@@ -63,7 +63,7 @@ func Bootstrap(p []Plugin, f []Plugin, l Lifecycle, c *Config, a *fiber.App) {
 			return nil
 		},
 		func(ctx context.Context) error {
-			err := a.Shutdown()
+			err := s.Run(ctx, a)
 			fmt.Printf("%v 已关闭\n", c.Name)
 			return err
 		},
@@ -85,7 +85,7 @@ func TestBootstrap(t *testing.T) {
 	lifecycle.On("Append", mock.AnythingOfType("func(context.Context) error"), mock.AnythingOfType("func(context.Context) error")).Return()
 
 	// 调用被测函数
-	Bootstrap([]Plugin{plugin}, []Plugin{filter}, lifecycle, config, app)
+	Bootstrap([]Plugin{plugin}, []Plugin{filter}, lifecycle, config, app, nil)
 
 	// 验证路由是否正确设置
 	// 添加测试路由
@@ -142,7 +142,7 @@ func TestBootstrap_ComplexRoutes(t *testing.T) {
 	lifecycle.On("Append", mock.AnythingOfType("func(context.Context) error"), mock.AnythingOfType("func(context.Context) error")).Return()
 
 	// 调用被测函数
-	Bootstrap([]Plugin{apiPlugin, userPlugin, authPlugin, authPlugin2}, []Plugin{logFilter}, lifecycle, config, app)
+	Bootstrap([]Plugin{apiPlugin, userPlugin, authPlugin, authPlugin2}, []Plugin{logFilter}, lifecycle, config, app, nil)
 
 	// 验证所有mock对象的调用
 	apiPlugin.AssertExpectations(t)
@@ -168,7 +168,7 @@ func TestBootstrap_EmptyBasePath(t *testing.T) {
 	lifecycle.On("Append", mock.AnythingOfType("func(context.Context) error"), mock.AnythingOfType("func(context.Context) error")).Return()
 
 	// 调用被测函数
-	Bootstrap([]Plugin{emptyPlugin}, []Plugin{}, lifecycle, config, app)
+	Bootstrap([]Plugin{emptyPlugin}, []Plugin{}, lifecycle, config, app, nil)
 
 	// 验证mock对象
 	emptyPlugin.AssertExpectations(t)
@@ -31,6 +31,20 @@ type Cache interface {
 	Unlock(ctx context.Context, key, owner string) error
 }
 
+// Stats 缓存的命中统计，计数自实例创建起单调递增
+type Stats struct {
+	Hits      uint64 // Get 命中次数
+	Misses    uint64 // Get 未命中次数
+	Evictions uint64 // 容量不足时被淘汰的 key 数
+}
+
+// Reporter 提供命中统计的缓存实现，监控指标通过类型断言采集，未实现的 provider 不上报。
+type Reporter interface {
+	Stats() Stats
+}
+
+// 需要释放资源的 provider 同时实现 io.Closer，应用关闭时在断开 Redis 等连接之前调用。
+
 type factory func(conn any) (Cache, error)
 
 var current struct {
@@ -16,6 +16,7 @@ func init() {
 		c, err := ristretto.NewCache(&ristretto.Config[string, []byte]{
 			NumCounters: 1e5, MaxCost: 1 << 30, BufferItems: 64,
 			Cost: func(val []byte) int64 { return int64(len(val)) }, IgnoreInternalCost: true,
+			Metrics: true,
 		})
 		if err != nil {
 			return nil, err
@@ -63,6 +64,19 @@ func (my *memoryCache) Flush(_ context.Context, tags ...string) error {
 	return nil
 }
 
+// Close 等待缓冲区中的写入完成后释放缓存，应用关闭时调用
+func (my *memoryCache) Close() error {
+	my.cache.Wait()
+	my.cache.Close()
+	return nil
+}
+
+// Stats 命中和淘汰计数取自 ristretto 的内置统计
+func (my *memoryCache) Stats() cache.Stats {
+	m := my.cache.Metrics
+	return cache.Stats{Hits: m.Hits(), Misses: m.Misses(), Evictions: m.KeysEvicted()}
+}
+
 func (my *memoryCache) TryLock(_ context.Context, key, owner string, ttl time.Duration) (bool, error) {
 	if owner == "" || ttl <= 0 {
 		return false, errors.New("cache: TryLock requires non-empty owner and positive ttl")
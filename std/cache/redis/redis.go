@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/ichaly/ideabase/std/cache"
@@ -22,17 +23,28 @@ func init() {
 }
 
 type redisCache struct {
-	rdb goredis.UniversalClient
+	rdb    goredis.UniversalClient
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
 func (my *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	data, err := my.rdb.Get(ctx, key).Bytes()
 	if errors.Is(err, goredis.Nil) {
+		my.misses.Add(1)
 		return nil, cache.ErrNotFound
 	}
+	if err == nil {
+		my.hits.Add(1)
+	}
 	return data, err
 }
 
+// Stats 只统计本实例的命中情况；淘汰发生在 Redis 服务端，由其 evicted_keys 指标反映，这里恒为 0
+func (my *redisCache) Stats() cache.Stats {
+	return cache.Stats{Hits: my.hits.Load(), Misses: my.misses.Load()}
+}
+
 func (my *redisCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration, tags ...string) error {
 	p := my.rdb.Pipeline()
 	p.Set(ctx, key, val, ttl)
@@ -22,3 +22,8 @@ func NewConfig(k *Konfig) (*Config, error) {
 func (my *Config) IsDebug() bool {
 	return my.Mode == "development" || my.Mode == "dev"
 }
+
+// IsProd 判断是否为生产模式
+func (my *Config) IsProd() bool {
+	return my.Mode == "production" || my.Mode == "prod"
+}
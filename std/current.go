@@ -1,6 +1,7 @@
 package std
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -12,6 +13,11 @@ import (
 // CurrentUserKey 统一在 Fiber 上下文中存放当前用户标识，避免重复定义。
 const CurrentUserKey = "__current_user_id__"
 
+// ClaimsContextKey 在上下文中存放已校验的 JWT 负载，供授权规则读取角色等声明。
+var ClaimsContextKey = claimsContextKeyType{}
+
+type claimsContextKeyType struct{}
+
 // AuthExtract 从 JWT 负载解析出校验后的登录用户标识，借助二次序列化/反序列化触发 Id 的自定义 JSON 逻辑，从而兼容 sqids 与数值形式。
 func AuthExtract(claims jwt.MapClaims) (Id, error) {
 	if claims == nil {
@@ -39,6 +45,39 @@ func AuthPersist(c fiber.Ctx, userID Id) error {
 	return nil
 }
 
+// AuthVerified 作为 JWT 中间件校验通过后的处理，解析用户标识并将用户与负载一起写入上下文，授权规则据此读取角色和声明。
+func AuthVerified(c fiber.Ctx, claims jwt.MapClaims) error {
+	userID, err := AuthExtract(claims)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+	if err = AuthPersist(c, userID); err != nil {
+		return err
+	}
+	c.SetContext(SetAuthClaims(c.Context(), claims))
+	return nil
+}
+
+// SetAuthClaims 将已校验的 JWT 负载写入上下文，供授权规则读取角色和其他声明。
+func SetAuthClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(claims) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, ClaimsContextKey, claims)
+}
+
+// GetAuthClaims 读取上下文中的 JWT 负载，未登录时返回空。
+func GetAuthClaims(ctx context.Context) jwt.MapClaims {
+	if ctx == nil {
+		return nil
+	}
+	claims, _ := ctx.Value(ClaimsContextKey).(jwt.MapClaims)
+	return claims
+}
+
 // AuthCurrent 暴露统一的上下文读取能力，避免 API 层重复解析。
 func AuthCurrent(c fiber.Ctx) Id {
 	return currentUserFromFiber(c)
@@ -0,0 +1,49 @@
+package std
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthVerified(t *testing.T) {
+	var claims jwt.MapClaims
+	app := fiber.New()
+	// 模拟 JWT 中间件：校验通过后交给 AuthVerified 写入用户和负载
+	app.Use(func(c fiber.Ctx) error {
+		if err := AuthVerified(c, claims); err != nil {
+			return err
+		}
+		return c.Next()
+	})
+
+	var user Id
+	var got jwt.MapClaims
+	app.Get("/me", func(c fiber.Ctx) error {
+		user, got = GetAuditUser(c.Context()), GetAuthClaims(c.Context())
+		return c.SendStatus(http.StatusOK)
+	})
+
+	t.Run("负载写入上下文", func(t *testing.T) {
+		claims = jwt.MapClaims{"sub": "42", "role": "admin", "org": "7"}
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/me", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, Id(42), user)
+		assert.Equal(t, claims, got)
+	})
+
+	t.Run("主体标识缺失", func(t *testing.T) {
+		user, got = 0, nil
+		claims = jwt.MapClaims{"role": "admin"}
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/me", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Nil(t, got)
+	})
+}
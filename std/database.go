@@ -1,12 +1,15 @@
 package std
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/url"
 	"time"
 
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std/internal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // Dialector 数据库方言工厂函数
@@ -19,9 +22,9 @@ func RegisterDatabase(scheme string, d Dialector) {
 	dialectors[scheme] = d
 }
 
-// NewDatabase 根据 Config URL 创建数据库连接
-func NewDatabase(e []interface{}, p []gorm.Plugin, c *Config) (*gorm.DB, error) {
-	u, err := url.Parse(c.Database)
+// dialector 按 URL 的 scheme 查找已注册的方言
+func dialector(uri string) (gorm.Dialector, error) {
+	u, err := url.Parse(uri)
 	if err != nil {
 		return nil, fmt.Errorf("database: invalid url: %w", err)
 	}
@@ -29,9 +32,20 @@ func NewDatabase(e []interface{}, p []gorm.Plugin, c *Config) (*gorm.DB, error)
 	if !ok {
 		return nil, fmt.Errorf("database driver '%s' not registered", u.Scheme)
 	}
-	db, err := gorm.Open(d(c.Database), &gorm.Config{
-		PrepareStmt: u.Scheme == "mysql" || !c.IsDebug(),
-		Logger:      logger.Default.LogMode(logger.Info),
+	return d(uri), nil
+}
+
+// NewDatabase 根据 Config URL 创建数据库连接，配置副本时启用读写分离
+func NewDatabase(e []interface{}, p []gorm.Plugin, c *Config) (*gorm.DB, error) {
+	u, err := url.Parse(c.Database)
+	if err != nil {
+		return nil, fmt.Errorf("database: invalid url: %w", err)
+	}
+	db, err := openDatabase(c.Database, c.Retry, func() *gorm.Config {
+		return &gorm.Config{
+			PrepareStmt: u.Scheme == "mysql" || !c.IsDebug(),
+			Logger:      NewGormLogger(c),
+		}
 	})
 	if err != nil {
 		return nil, err
@@ -41,7 +55,12 @@ func NewDatabase(e []interface{}, p []gorm.Plugin, c *Config) (*gorm.DB, error)
 			return nil, err
 		}
 	}
-	if c.IsDebug() {
+	// 启用版本化迁移后由迁移文件管理表结构，不再执行AutoMigrate
+	if c.Migrate.Auto {
+		if _, err = NewMigrator(db, c).Up(context.Background()); err != nil {
+			return nil, err
+		}
+	} else if c.IsDebug() {
 		for _, v := range e {
 			tx := db
 			if !skipAutoMigrate(v) {
@@ -63,8 +82,82 @@ func NewDatabase(e []interface{}, p []gorm.Plugin, c *Config) (*gorm.DB, error)
 	if err != nil {
 		return nil, fmt.Errorf("database: failed to get sql.DB: %w", err)
 	}
-	sqlDb.SetMaxIdleConns(5)
-	sqlDb.SetMaxOpenConns(90)
-	sqlDb.SetConnMaxLifetime(5 * time.Minute)
+	pools := []gorm.ConnPool{sqlDb}
+	// 迁移完成后再启用读写分离，迁移中的元数据查询始终读主库
+	if len(c.Replicas) > 0 {
+		replicas := make([]gorm.Dialector, len(c.Replicas))
+		for i, r := range c.Replicas {
+			if replicas[i], err = dialector(r); err != nil {
+				return nil, err
+			}
+		}
+		r := NewReplicas(replicas...)
+		if err = db.Use(r); err != nil {
+			return nil, err
+		}
+		pools = append(pools, r.pools...)
+	}
+	for _, v := range pools {
+		setPool(v, c.Pool)
+	}
 	return db, nil
 }
+
+// openDatabase 连接数据库，失败时按指数退避重试，数据库晚于应用就绪时不必立即退出
+// 每次尝试使用新的方言和配置，失败的连接池随即关闭
+func openDatabase(uri string, r internal.RetryConfig, config func() *gorm.Config) (*gorm.DB, error) {
+	wait := r.Backoff
+	for attempt := 1; ; attempt++ {
+		d, err := dialector(uri)
+		if err != nil {
+			return nil, err
+		}
+		db, err := gorm.Open(d, config())
+		if err == nil {
+			return db, nil
+		}
+		if db != nil && db.ConnPool != nil {
+			if sqlDb, e := db.DB(); e == nil {
+				_ = sqlDb.Close()
+			}
+		}
+		if attempt >= r.Attempts {
+			return nil, fmt.Errorf("database: connect failed after %d attempts: %w", attempt, err)
+		}
+		log.Warn().Err(err).Int("attempt", attempt).Dur("wait", wait).Msg("数据库连接失败，等待重试")
+		time.Sleep(wait)
+		if wait *= 2; r.MaxBackoff > 0 && wait > r.MaxBackoff {
+			wait = r.MaxBackoff
+		}
+	}
+}
+
+// 连接池的默认配置，未配置时的连接数和存活时间
+const (
+	defaultMaxOpen     = 90
+	defaultMaxIdle     = 5
+	defaultMaxLifetime = 5 * time.Minute
+)
+
+// setPool 将连接池配置应用到连接，连接数和存活时间为零时使用默认配置，空闲时间为零时保持驱动默认值
+func setPool(p gorm.ConnPool, c internal.PoolConfig) {
+	db, ok := p.(*sql.DB)
+	if !ok {
+		return
+	}
+	if c.MaxOpen <= 0 {
+		c.MaxOpen = defaultMaxOpen
+	}
+	if c.MaxIdle <= 0 {
+		c.MaxIdle = defaultMaxIdle
+	}
+	if c.MaxLifetime <= 0 {
+		c.MaxLifetime = defaultMaxLifetime
+	}
+	db.SetMaxOpenConns(c.MaxOpen)
+	db.SetMaxIdleConns(c.MaxIdle)
+	db.SetConnMaxLifetime(c.MaxLifetime)
+	if c.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(c.MaxIdleTime)
+	}
+}
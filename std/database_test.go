@@ -0,0 +1,81 @@
+package std
+
+import (
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ichaly/ideabase/std/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// flakyDialector 前若干次初始化失败的方言，模拟数据库晚于应用就绪
+type flakyDialector struct {
+	gorm.Dialector
+	fails *atomic.Int32
+}
+
+func (my flakyDialector) Initialize(db *gorm.DB) error {
+	if my.fails.Add(-1) >= 0 {
+		return errors.New("connection refused")
+	}
+	return my.Dialector.Initialize(db)
+}
+
+func TestNewDatabase(t *testing.T) {
+	fails := &atomic.Int32{}
+	RegisterDatabase("flaky", func(string) gorm.Dialector {
+		return flakyDialector{Dialector: sqlite.Open("file:" + t.Name() + "?mode=memory&cache=private"), fails: fails}
+	})
+	RegisterDatabase("replica", func(string) gorm.Dialector {
+		return sqlite.Open("file:" + t.Name() + "_replica?mode=memory&cache=private")
+	})
+	cfg := mockConfig("TestApp", "production", "8080")
+	cfg.Database, cfg.Replicas = "flaky://test", []string{"replica://test"}
+	cfg.SQLLog.Level = "silent"
+	cfg.Pool.MaxOpen, cfg.Pool.MaxIdle = 3, 2
+	cfg.Retry.Attempts, cfg.Retry.Backoff, cfg.Retry.MaxBackoff = 3, 10*time.Millisecond, 15*time.Millisecond
+
+	t.Run("失败后重试", func(t *testing.T) {
+		fails.Store(2)
+		start := time.Now()
+		db, err := NewDatabase(nil, nil, cfg)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond, "等待时间翻倍且不超过上限")
+
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+		replicas := db.Config.Plugins["std:replicas"].(*Replicas)
+		require.Len(t, replicas.pools, 1)
+		assert.Equal(t, 3, replicas.pools[0].(*sql.DB).Stats().MaxOpenConnections, "副本使用相同的连接池配置")
+		require.NoError(t, replicas.Close())
+		require.NoError(t, sqlDB.Close())
+	})
+
+	t.Run("未配置连接池时使用默认配置", func(t *testing.T) {
+		fails.Store(0)
+		c := *cfg
+		c.Pool = internal.PoolConfig{}
+		db, err := NewDatabase(nil, nil, &c)
+		require.NoError(t, err)
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		assert.Equal(t, 90, sqlDB.Stats().MaxOpenConnections)
+		replicas := db.Config.Plugins["std:replicas"].(*Replicas)
+		assert.Equal(t, 90, replicas.pools[0].(*sql.DB).Stats().MaxOpenConnections)
+		require.NoError(t, replicas.Close())
+		require.NoError(t, sqlDB.Close())
+	})
+
+	t.Run("超过次数后返回错误", func(t *testing.T) {
+		fails.Store(3)
+		_, err := NewDatabase(nil, nil, cfg)
+		assert.ErrorContains(t, err, "after 3 attempts: connection refused")
+	})
+}
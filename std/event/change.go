@@ -0,0 +1,17 @@
+package event
+
+// 表变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// TableChange 数据表变更事件，订阅方据此判断查询结果是否失效，不携带行数据。
+type TableChange struct {
+	Table  string `json:"table"`
+	Action string `json:"action"`
+}
+
+// TopicTableChange 数据表变更主题，所有表共用一个主题，由订阅方按表名过滤。
+const TopicTableChange Topic[TableChange] = "db:table:change"
@@ -0,0 +1,30 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ichaly/ideabase/utl"
+)
+
+// headersKey 信封中消息头的字段名，带前缀避免与业务载荷的字段冲突
+const headersKey = "__headers"
+
+// envelope 带消息头的载荷，消息头用于跨进程传递链路追踪上下文
+type envelope[T any] struct {
+	Headers map[string]string `json:"__headers"`
+	Payload T                 `json:"__payload"`
+}
+
+// openEnvelope 解开带消息头的载荷，普通载荷原样返回
+// 只有包含消息头字段的 JSON 对象才尝试按信封解析，其他发布方直接写入的载荷不受影响
+func openEnvelope(data []byte) ([]byte, map[string]string) {
+	if !bytes.Contains(data, []byte(`"`+headersKey+`"`)) {
+		return data, nil
+	}
+	var e envelope[json.RawMessage]
+	if err := utl.Unmarshal(data, &e); err != nil || e.Headers == nil || e.Payload == nil {
+		return data, nil
+	}
+	return e.Payload, e.Headers
+}
@@ -3,18 +3,76 @@ package event
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ichaly/ideabase/log"
 	"github.com/ichaly/ideabase/std/event/internal/driver"
 	"github.com/ichaly/ideabase/utl"
 	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// tracer 发布与订阅处理的链路追踪器，未启用时全局提供者为空实现
+var tracer = otel.Tracer("github.com/ichaly/ideabase/std/event")
+
 // Bus 业务层入口，发布/订阅走 Publish[T]/Subscribe[T]。
 type Bus struct {
-	d driver.Driver
+	d      driver.Driver
+	topics sync.Map // topic -> *counters
+}
+
+// TopicStats 单个主题的发布与处理计数，自总线创建起单调递增
+type TopicStats struct {
+	Published     uint64 // 发布成功次数
+	PublishErrors uint64 // 发布失败次数
+	HandlerErrors uint64 // 订阅 handler 返回错误的次数
+}
+
+type counters struct {
+	published     atomic.Uint64
+	publishErrors atomic.Uint64
+	handlerErrors atomic.Uint64
+}
+
+// counters 返回主题的计数器，首次使用时创建
+func (my *Bus) counters(topic string) *counters {
+	if c, ok := my.topics.Load(topic); ok {
+		return c.(*counters)
+	}
+	c, _ := my.topics.LoadOrStore(topic, &counters{})
+	return c.(*counters)
+}
+
+// Ping 检查底层传输是否可用，供健康检查使用
+func (my *Bus) Ping(ctx context.Context) error {
+	return my.d.Ping(ctx)
+}
+
+// Close 关闭总线的订阅，底层连接由创建方负责关闭
+func (my *Bus) Close() error {
+	return my.d.Close()
+}
+
+// Stats 返回各主题的发布与处理计数快照，供监控指标采集
+func (my *Bus) Stats() map[string]TopicStats {
+	stats := make(map[string]TopicStats)
+	my.topics.Range(func(k, v any) bool {
+		c := v.(*counters)
+		stats[k.(string)] = TopicStats{
+			Published:     c.published.Load(),
+			PublishErrors: c.publishErrors.Load(),
+			HandlerErrors: c.handlerErrors.Load(),
+		}
+		return true
+	})
+	return stats
 }
 
 // Topic 绑定一条 bus 主题到其载荷类型。底层是 string，可直接作日志字段/配置 key；
@@ -58,21 +116,60 @@ func New(rdb redis.UniversalClient, nc *nats.Conn, db *gorm.DB) (*Bus, error) {
 }
 
 // Publish 类型化发布，topic 的类型参数必须与 payload 类型一致。
+// 启用链路追踪时载荷包装为带消息头的信封，消息头携带 traceparent，订阅方据此延续链路。
 func Publish[T any](ctx context.Context, bus *Bus, topic Topic[T], payload T) error {
-	return bus.d.Publish(ctx, string(topic), payload)
+	name := string(topic)
+	ctx, span := tracer.Start(ctx, "publish "+name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination.name", name)),
+	)
+	defer span.End()
+
+	headers := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	var body any = payload
+	if len(headers) > 0 {
+		body = envelope[T]{Headers: headers, Payload: payload}
+	}
+	err := bus.d.Publish(ctx, name, body)
+	if err != nil {
+		bus.counters(name).publishErrors.Add(1)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		bus.counters(name).published.Add(1)
+	}
+	return err
 }
 
 // Subscribe 类型化订阅，字节载荷自动按 T 反序列化。
 // 反序列化失败打日志丢弃，不回传 driver，避免坏载荷阻塞业务总线（R12 语义）。
+// 带消息头的载荷先解开信封，以消息头中的链路上下文为父 span 调用 handler。
 func Subscribe[T any](ctx context.Context, bus *Bus, topic Topic[T], handler func(context.Context, T) error) error {
 	name := string(topic)
+	stats := bus.counters(name)
 	return bus.d.Subscribe(ctx, name, func(c context.Context, data []byte) error {
+		data, headers := openEnvelope(data)
+		if len(headers) > 0 {
+			c = otel.GetTextMapPropagator().Extract(c, propagation.MapCarrier(headers))
+		}
+		c, span := tracer.Start(c, "process "+name,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("messaging.destination.name", name)),
+		)
+		defer span.End()
+
 		var payload T
 		if err := utl.Unmarshal(data, &payload); err != nil {
 			log.Error().Err(err).Str("topic", name).Msg("event: drop malformed payload")
 			return nil
 		}
-		return handler(c, payload)
+		err := handler(c, payload)
+		if err != nil {
+			stats.handlerErrors.Add(1)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
 	})
 }
-
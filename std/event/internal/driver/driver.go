@@ -16,6 +16,8 @@ type Handler func(ctx context.Context, payload []byte) error
 type Driver interface {
 	Publish(ctx context.Context, topic string, payload any) error
 	Subscribe(ctx context.Context, topic string, handler Handler) error
+	// Ping 检查底层连接是否可用，供健康检查使用。
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -63,6 +63,11 @@ func (my *memoryEvent) invoke(ctx context.Context, topic string, body []byte, h
 	}
 }
 
+// Ping 进程内分发无外部连接，始终可用。
+func (my *memoryEvent) Ping(context.Context) error {
+	return nil
+}
+
 func (my *memoryEvent) Subscribe(_ context.Context, topic string, handler driver.Handler) error {
 	my.mu.Lock()
 	defer my.mu.Unlock()
@@ -70,4 +75,10 @@ func (my *memoryEvent) Subscribe(_ context.Context, topic string, handler driver
 	return nil
 }
 
-func (my *memoryEvent) Close() error { return nil }
+// Close 移除全部订阅，之后发布的事件不再分发。
+func (my *memoryEvent) Close() error {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.handlers = make(map[string][]driver.Handler)
+	return nil
+}
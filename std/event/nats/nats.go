@@ -2,8 +2,10 @@ package nats
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/ichaly/ideabase/log"
 	"github.com/ichaly/ideabase/std/event"
@@ -25,7 +27,9 @@ func init() {
 }
 
 type natsEvent struct {
-	nc *gonats.Conn
+	nc   *gonats.Conn
+	mu   sync.Mutex
+	subs []*gonats.Subscription
 }
 
 func (my *natsEvent) Publish(_ context.Context, topic string, payload any) error {
@@ -37,19 +41,42 @@ func (my *natsEvent) Publish(_ context.Context, topic string, payload any) error
 }
 
 func (my *natsEvent) Subscribe(_ context.Context, topic string, handler driver.Handler) error {
-	_, err := my.nc.Subscribe(natsTopic(topic), func(msg *gonats.Msg) {
+	sub, err := my.nc.Subscribe(natsTopic(topic), func(msg *gonats.Msg) {
 		go func(data []byte) {
 			if err := handler(context.Background(), data); err != nil {
 				log.Warn().Err(err).Str("topic", topic).Msg("nats event handler error")
 			}
 		}(msg.Data)
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	my.mu.Lock()
+	my.subs = append(my.subs, sub)
+	my.mu.Unlock()
+	return nil
+}
+
+// Ping 断线重连期间视为不可用，已连接时往返一次确认服务端可达。
+func (my *natsEvent) Ping(ctx context.Context) error {
+	if !my.nc.IsConnected() {
+		return fmt.Errorf("event/nats: connection %s", my.nc.Status())
+	}
+	return my.nc.FlushWithContext(ctx)
 }
 
+// Close 只退订本总线的订阅，连接由创建方在关闭流程的最后关闭。
 func (my *natsEvent) Close() error {
-	my.nc.Close()
-	return nil
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	var errs []error
+	for _, sub := range my.subs {
+		if err := sub.Unsubscribe(); err != nil && !errors.Is(err, gonats.ErrConnectionClosed) {
+			errs = append(errs, err)
+		}
+	}
+	my.subs = nil
+	return errors.Join(errs...)
 }
 
 func natsTopic(topic string) string {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ichaly/ideabase/log"
@@ -36,6 +37,8 @@ type postgresEvent struct {
 	cancel   context.CancelFunc
 	stop     context.Context
 	stopFunc context.CancelFunc
+	online   atomic.Bool   // LISTEN 连接是否在线，断线重连期间为 false
+	done     chan struct{} // 监听协程退出后关闭
 }
 
 func newPostgresEvent(db *gorm.DB) *postgresEvent {
@@ -44,9 +47,11 @@ func newPostgresEvent(db *gorm.DB) *postgresEvent {
 		dsn = d.Config.DSN
 	}
 	ctx, cancel := context.WithCancel(context.Background())
-	e := &postgresEvent{db: db, dsn: dsn, handlers: make(map[string][]driver.Handler), stop: ctx, stopFunc: cancel}
+	e := &postgresEvent{db: db, dsn: dsn, handlers: make(map[string][]driver.Handler), stop: ctx, stopFunc: cancel, done: make(chan struct{})}
 	if dsn != "" {
 		go e.listenLoop()
+	} else {
+		close(e.done)
 	}
 	return e
 }
@@ -72,19 +77,37 @@ func (my *postgresEvent) Subscribe(_ context.Context, topic string, handler driv
 	return nil
 }
 
+// Ping 检查数据库可达；已配置 DSN 时 LISTEN 连接断开也视为不可用，此时收不到通知。
+func (my *postgresEvent) Ping(ctx context.Context) error {
+	sqlDB, err := my.db.DB()
+	if err != nil {
+		return err
+	}
+	if err = sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+	if my.dsn != "" && !my.online.Load() {
+		return errors.New("event/postgres: listener disconnected")
+	}
+	return nil
+}
+
+// Close 停止监听并等待监听协程退出，LISTEN 连接随之关闭。
 func (my *postgresEvent) Close() error {
 	my.stopFunc()
+	<-my.done
 	return nil
 }
 
 func (my *postgresEvent) listenLoop() {
+	defer close(my.done)
 	for {
 		select {
 		case <-my.stop.Done():
 			return
 		default:
 		}
-		if err := my.runListen(); err != nil {
+		if err := my.runListen(); err != nil && my.stop.Err() == nil {
 			log.Error().Err(err).Msg("postgres event listener disconnected, retrying in 5s")
 			select {
 			case <-time.After(5 * time.Second):
@@ -96,11 +119,13 @@ func (my *postgresEvent) listenLoop() {
 }
 
 func (my *postgresEvent) runListen() error {
-	conn, err := pgx.Connect(context.Background(), my.dsn)
+	conn, err := pgx.Connect(my.stop, my.dsn)
 	if err != nil {
 		return err
 	}
 	defer conn.Close(context.Background())
+	my.online.Store(true)
+	defer my.online.Store(false)
 
 	listening := make(map[string]bool)
 	for {
@@ -112,7 +137,8 @@ func (my *postgresEvent) runListen() error {
 		if my.cancel != nil {
 			my.cancel()
 		}
-		ctx, cancel := context.WithCancel(context.Background())
+		// 等待通知的上下文派生自 stop，关闭时立即中断等待
+		ctx, cancel := context.WithCancel(my.stop)
 		my.cancel = cancel
 		my.lock.Unlock()
 
@@ -129,6 +155,9 @@ func (my *postgresEvent) runListen() error {
 		notification, err := conn.WaitForNotification(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
+				if my.stop.Err() != nil {
+					return nil
+				}
 				continue
 			}
 			cancel()
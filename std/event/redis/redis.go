@@ -54,6 +54,10 @@ func (my *redisEvent) Subscribe(ctx context.Context, topic string, handler drive
 	return my.pubsub.Subscribe(ctx, topic)
 }
 
+func (my *redisEvent) Ping(ctx context.Context) error {
+	return my.rdb.Ping(ctx).Err()
+}
+
 func (my *redisEvent) Close() error {
 	if my.pubsub != nil {
 		return my.pubsub.Close()
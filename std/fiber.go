@@ -107,6 +107,11 @@ func NewFiber(c *Config, v *Validator, opts ...FiberOption) *fiber.App {
 	}
 	app := fiber.New(conf)
 
+	// 链路追踪中间件，最先注册使span覆盖其余中间件
+	if c.Tracing.Enable {
+		app.Use(newTracingHandler())
+	}
+
 	// 注册基础中间件
 	app.Use(requestid.New()) // 请求ID中间件
 	app.Use(recover.New())   // 异常恢复中间件
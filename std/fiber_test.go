@@ -165,7 +165,7 @@ func TestHealthCheck(t *testing.T) {
 	app := NewFiber(cfg, nil)
 
 	// 注册健康检查插件
-	health := NewHealth()
+	health := NewHealth(cfg, nil)
 	health.Bind(app.Group(health.Path()))
 
 	// 测试存活检测端点
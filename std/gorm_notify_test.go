@@ -0,0 +1,91 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ichaly/ideabase/std/event"
+	_ "github.com/ichaly/ideabase/std/event/memory"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestGormNotifyPublishesTableChange(t *testing.T) {
+	db := openTestDB(t)
+	notify := NewGormNotify()
+	require.NoError(t, db.Use(notify))
+	require.NoError(t, db.AutoMigrate(&cacheUser{}))
+
+	// 未注入总线时写入不受影响
+	require.NoError(t, db.Create(&cacheUser{ID: 1, Name: "Tom"}).Error)
+
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	notify.Attach(bus)
+
+	var changes []event.TableChange
+	require.NoError(t, event.Subscribe(context.Background(), bus, event.TopicTableChange, func(_ context.Context, c event.TableChange) error {
+		changes = append(changes, c)
+		return nil
+	}))
+
+	require.NoError(t, db.Create(&cacheUser{ID: 2, Name: "Jerry"}).Error)
+	require.NoError(t, db.Model(&cacheUser{ID: 2}).Update("name", "Spike").Error)
+	require.NoError(t, db.Delete(&cacheUser{ID: 1}).Error)
+	// 写入失败不广播
+	require.Error(t, db.Create(&cacheUser{ID: 2, Name: "Jerry"}).Error)
+
+	require.Equal(t, []event.TableChange{
+		{Table: "cache_users", Action: event.ActionCreate},
+		{Table: "cache_users", Action: event.ActionUpdate},
+		{Table: "cache_users", Action: event.ActionDelete},
+	}, changes)
+}
+
+func TestGormNotifyAfterCommit(t *testing.T) {
+	db := openTestDB(t)
+	notify := NewGormNotify()
+	require.NoError(t, db.Use(notify))
+	require.NoError(t, db.AutoMigrate(&cacheUser{}))
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	notify.Attach(bus)
+
+	var changes []event.TableChange
+	require.NoError(t, event.Subscribe(context.Background(), bus, event.TopicTableChange, func(_ context.Context, c event.TableChange) error {
+		changes = append(changes, c)
+		return nil
+	}))
+	service := NewBaseService(db)
+	created := event.TableChange{Table: "cache_users", Action: event.ActionCreate}
+
+	t.Run("提交后广播", func(t *testing.T) {
+		changes = nil
+		require.NoError(t, service.WithTransaction(context.Background(), func(ctx context.Context) error {
+			require.NoError(t, service.DB(ctx).Create(&cacheUser{ID: 1, Name: "Tom"}).Error)
+			require.Empty(t, changes, "提交前不广播")
+			return nil
+		}))
+		require.Equal(t, []event.TableChange{created}, changes)
+	})
+
+	t.Run("回滚不广播", func(t *testing.T) {
+		changes = nil
+		rollback := errors.New("rollback")
+		err := service.WithTransaction(context.Background(), func(ctx context.Context) error {
+			require.NoError(t, service.DB(ctx).Create(&cacheUser{ID: 2, Name: "Jerry"}).Error)
+			return rollback
+		})
+		require.ErrorIs(t, err, rollback)
+		require.Empty(t, changes)
+	})
+
+	t.Run("没有提交钩子的事务不广播", func(t *testing.T) {
+		changes = nil
+		require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&cacheUser{ID: 3, Name: "Spike"}).Error
+		}))
+		require.Empty(t, changes)
+	})
+}
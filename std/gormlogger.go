@@ -0,0 +1,95 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ichaly/ideabase/log"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm/logger"
+)
+
+var gormLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// GormLogger 通过 log 包输出的 GORM 日志适配器
+// 执行失败的语句按 Error 记录，超过慢查询阈值的按 Warn 记录，Info 级别下记录所有语句
+// 生产模式下只记录带占位符的 SQL，参数不写入日志
+type GormLogger struct {
+	level  logger.LogLevel
+	slow   time.Duration
+	redact bool
+}
+
+// NewGormLogger 按 SQL 日志配置创建 GORM 日志适配器
+func NewGormLogger(c *Config) logger.Interface {
+	level, ok := gormLevels[strings.ToLower(c.SQLLog.Level)]
+	if !ok {
+		level = logger.Warn
+		if c.IsDebug() {
+			level = logger.Info
+		}
+	}
+	return &GormLogger{level: level, slow: c.SQLLog.Slow, redact: c.IsProd()}
+}
+
+func (my *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l := *my
+	l.level = level
+	return &l
+}
+
+func (my *GormLogger) Info(_ context.Context, msg string, data ...interface{}) {
+	if my.level >= logger.Info {
+		log.Info().Msgf(msg, data...)
+	}
+}
+
+func (my *GormLogger) Warn(_ context.Context, msg string, data ...interface{}) {
+	if my.level >= logger.Warn {
+		log.Warn().Msgf(msg, data...)
+	}
+}
+
+func (my *GormLogger) Error(_ context.Context, msg string, data ...interface{}) {
+	if my.level >= logger.Error {
+		log.Error().Msgf(msg, data...)
+	}
+}
+
+// Trace 记录语句的耗时和影响行数，未找到记录不视为错误
+func (my *GormLogger) Trace(_ context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	var evt *zerolog.Event
+	msg := "SQL"
+	switch {
+	case err != nil && !errors.Is(err, logger.ErrRecordNotFound) && my.level >= logger.Error:
+		evt, msg = log.Error().Err(err), "SQL执行失败"
+	case my.slow > 0 && elapsed > my.slow && my.level >= logger.Warn:
+		evt, msg = log.Warn().Dur("threshold", my.slow), "慢查询"
+	case my.level >= logger.Info:
+		evt = log.Info()
+	default:
+		return
+	}
+	sql, rows := fc()
+	evt = evt.Str("sql", sql).Dur("elapsed", elapsed)
+	if rows >= 0 {
+		evt = evt.Int64("rows", rows)
+	}
+	evt.Msg(msg)
+}
+
+// ParamsFilter 生产模式下去掉语句参数，GORM 据此生成不含参数值的 SQL
+func (my *GormLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if my.redact {
+		return sql, nil
+	}
+	return sql, params
+}
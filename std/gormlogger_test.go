@@ -0,0 +1,94 @@
+package std
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ichaly/ideabase/log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type loggerUser struct {
+	ID   uint
+	Name string
+}
+
+// captureLog 将全局日志临时写入缓冲区，测试结束后恢复
+func captureLog(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	l := log.GetDefault()
+	old := *l
+	*l = zerolog.New(buf)
+	t.Cleanup(func() { *l = old })
+	return buf
+}
+
+// entries 解析缓冲区中的日志
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var list []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		list = append(list, m)
+	}
+	buf.Reset()
+	return list
+}
+
+func TestGormLogger(t *testing.T) {
+	open := func(mode, level string, slow time.Duration) *gorm.DB {
+		cfg := mockConfig("TestApp", mode, "8080")
+		cfg.SQLLog.Level, cfg.SQLLog.Slow = level, slow
+		db, err := gorm.Open(sqlite.Open("file:"+t.Name()+mode+"?mode=memory&cache=private"), &gorm.Config{Logger: NewGormLogger(cfg)})
+		require.NoError(t, err)
+		require.NoError(t, db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)}).AutoMigrate(&loggerUser{}))
+		return db
+	}
+	buf := captureLog(t)
+
+	t.Run("开发模式默认记录所有语句和参数", func(t *testing.T) {
+		db := open("development", "", 0)
+		require.NoError(t, db.Create(&loggerUser{Name: "secret"}).Error)
+		list := entries(t, buf)
+		require.Len(t, list, 1)
+		assert.Equal(t, "info", list[0]["level"])
+		assert.Contains(t, list[0]["sql"], "secret")
+		assert.EqualValues(t, 1, list[0]["rows"])
+	})
+
+	t.Run("按级别过滤", func(t *testing.T) {
+		db := open("development", "warn", 0)
+		var u loggerUser
+		assert.Error(t, db.First(&u, 100).Error)
+		require.NoError(t, db.Create(&loggerUser{Name: "a"}).Error)
+		assert.Empty(t, entries(t, buf), "未找到记录不视为错误，正常语句不记录")
+
+		assert.Error(t, db.Exec("SELECT * FROM missing").Error)
+		list := entries(t, buf)
+		require.Len(t, list, 1)
+		assert.Equal(t, "error", list[0]["level"])
+		assert.Equal(t, "SQL执行失败", list[0]["message"])
+	})
+
+	t.Run("生产模式慢查询隐藏参数", func(t *testing.T) {
+		db := open("production", "", time.Nanosecond)
+		require.NoError(t, db.Create(&loggerUser{Name: "secret"}).Error)
+		list := entries(t, buf)
+		require.Len(t, list, 1)
+		assert.Equal(t, "warn", list[0]["level"])
+		assert.Equal(t, "慢查询", list[0]["message"])
+		assert.Contains(t, list[0]["sql"], "INSERT INTO `logger_users`")
+		assert.NotContains(t, list[0]["sql"], "secret")
+	})
+}
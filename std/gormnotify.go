@@ -0,0 +1,98 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std/event"
+	"gorm.io/gorm"
+)
+
+// GormNotify 在 GORM 写入提交后通过事件总线广播表变更，驱动订阅和实时查询重新执行。
+// 事件总线在数据库之后创建（postgres provider 依赖数据库连接），因此通过 Attach 延迟注入；
+// 未注入总线时不广播。默认事务在提交后广播；显式事务内的写入暂存在 BaseService.WithTransaction 的上下文中，
+// 提交成功后统一广播、回滚时丢弃，其他方式开启的事务没有提交钩子，其中的写入不广播。
+type GormNotify struct {
+	bus atomic.Pointer[event.Bus]
+}
+
+// NewGormNotify 创建表变更广播插件
+func NewGormNotify() *GormNotify {
+	return &GormNotify{}
+}
+
+func (my *GormNotify) Name() string { return "gorm:notify" }
+
+func (my *GormNotify) Initialize(db *gorm.DB) error {
+	name := my.Name()
+	return errors.Join(
+		db.Callback().Create().After("gorm:commit_or_rollback_transaction").Register(name+":create", my.notify(event.ActionCreate)),
+		db.Callback().Update().After("gorm:commit_or_rollback_transaction").Register(name+":update", my.notify(event.ActionUpdate)),
+		db.Callback().Delete().After("gorm:commit_or_rollback_transaction").Register(name+":delete", my.notify(event.ActionDelete)),
+	)
+}
+
+// Attach 注入事件总线，bus 为 nil 时停止广播
+func (my *GormNotify) Attach(bus *event.Bus) {
+	my.bus.Store(bus)
+}
+
+func (my *GormNotify) notify(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.DryRun || db.Statement.Table == "" {
+			return
+		}
+		bus := my.bus.Load()
+		if bus == nil {
+			return
+		}
+		ctx := db.Statement.Context
+		change := event.TableChange{Table: purgeTag(db), Action: action}
+		publish := func() {
+			if err := event.Publish(ctx, bus, event.TopicTableChange, change); err != nil {
+				log.Warn().Err(err).Str("table", change.Table).Msg("广播表变更失败")
+			}
+		}
+		// 默认事务此时已提交，连接已还原；仍在事务中说明是显式事务，等待提交
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			if p, ok := ctx.Value(pendingKey{}).(*pendingChanges); ok {
+				p.add(publish)
+			}
+			return
+		}
+		publish()
+	}
+}
+
+type pendingKey struct{}
+
+// pendingChanges 显式事务中暂存的表变更广播，提交后按写入顺序执行
+type pendingChanges struct {
+	mu   sync.Mutex
+	list []func()
+}
+
+// withPendingChanges 返回暂存表变更的上下文，事务提交后调用 flush 广播
+func withPendingChanges(ctx context.Context) (context.Context, *pendingChanges) {
+	p := &pendingChanges{}
+	return context.WithValue(ctx, pendingKey{}, p), p
+}
+
+func (my *pendingChanges) add(fn func()) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.list = append(my.list, fn)
+}
+
+func (my *pendingChanges) flush() {
+	my.mu.Lock()
+	list := my.list
+	my.list = nil
+	my.mu.Unlock()
+	for _, fn := range list {
+		fn()
+	}
+}
@@ -0,0 +1,78 @@
+package std
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "gorm:tracing:span"
+
+// GormTracing 为每条 GORM 语句创建 span 的链路追踪插件，父 span 取自语句的 Context
+type GormTracing struct{}
+
+// NewGormTracing 创建 GORM 链路追踪插件，未启用链路追踪时 span 为空实现
+func NewGormTracing() gorm.Plugin {
+	return &GormTracing{}
+}
+
+func (my *GormTracing) Name() string { return "gorm:tracing" }
+
+func (my *GormTracing) Initialize(db *gorm.DB) error {
+	name := my.Name()
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register(name+":before_create", my.before("create")),
+		cb.Create().After("*").Register(name+":after_create", my.after),
+		cb.Query().Before("*").Register(name+":before_query", my.before("query")),
+		cb.Query().After("*").Register(name+":after_query", my.after),
+		cb.Update().Before("*").Register(name+":before_update", my.before("update")),
+		cb.Update().After("*").Register(name+":after_update", my.after),
+		cb.Delete().Before("*").Register(name+":before_delete", my.before("delete")),
+		cb.Delete().After("*").Register(name+":after_delete", my.after),
+		cb.Row().Before("*").Register(name+":before_row", my.before("row")),
+		cb.Row().After("*").Register(name+":after_row", my.after),
+		cb.Raw().Before("*").Register(name+":before_raw", my.before("raw")),
+		cb.Raw().After("*").Register(name+":after_raw", my.after),
+	)
+}
+
+// before 开始 span 并写回语句的 Context，使嵌套调用成为子 span
+func (my *GormTracing) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		ctx, span := tracer.Start(db.Statement.Context, "gorm."+op, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// after 记录语句、表名和影响行数后结束 span，未找到记录不视为错误
+func (my *GormTracing) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("db.system.name", db.Dialector.Name()),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+	}
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
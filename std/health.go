@@ -1,16 +1,130 @@
 package std
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
+// startTime 进程启动时间，用于计算存活检查中的运行时长
+var startTime = time.Now()
+
+// Checker 健康检查项，组件通过ioc的health分组注册
+type Checker interface {
+	// Name 检查项名称，作为就绪检查结果中的键
+	Name() string
+	// Critical 关键检查项失败时就绪检查返回503，非关键项失败只降级
+	Critical() bool
+	// Check 执行检查，上下文带有配置的超时时间
+	Check(ctx context.Context) error
+}
+
+// checker 函数形式的检查项
+type checker struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) error
+}
+
+func (my *checker) Name() string                    { return my.name }
+func (my *checker) Critical() bool                  { return my.critical }
+func (my *checker) Check(ctx context.Context) error { return my.check(ctx) }
+
+// NewChecker 以函数创建检查项
+func NewChecker(name string, critical bool, check func(ctx context.Context) error) Checker {
+	return &checker{name: name, critical: critical, check: check}
+}
+
+// NewDatabaseChecker 检查数据库连接，为关键检查项
+func NewDatabaseChecker(db *gorm.DB) Checker {
+	if db == nil {
+		return nil
+	}
+	return NewChecker("database", true, func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// NewRedisChecker 检查Redis连接，未配置Redis时不注册
+func NewRedisChecker(rdb redis.UniversalClient) Checker {
+	if rdb == nil {
+		return nil
+	}
+	return NewChecker("redis", false, func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+}
+
+// NewNatsChecker 检查NATS连接，断线重连期间视为失败，未配置NATS时不注册
+func NewNatsChecker(nc *nats.Conn) Checker {
+	if nc == nil {
+		return nil
+	}
+	return NewChecker("nats", false, func(ctx context.Context) error {
+		if !nc.IsConnected() {
+			return fmt.Errorf("connection %s", nc.Status())
+		}
+		return nc.FlushWithContext(ctx)
+	})
+}
+
+// NewEventChecker 检查事件总线的底层传输
+func NewEventChecker(bus *event.Bus) Checker {
+	if bus == nil {
+		return nil
+	}
+	return NewChecker("event", false, bus.Ping)
+}
+
+// checkResult 单项检查的结果
+type checkResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Health 健康检查插件，提供通用、存活和就绪检查
+// 就绪检查并发执行所有检查项，结果在配置的时间内缓存，避免探针频繁访问外部依赖
 type Health struct {
+	checkers []Checker
+	timeout  time.Duration
+	ttl      time.Duration
+
+	closing atomic.Bool // 应用关闭中，就绪检查直接失败
+
+	mu      sync.Mutex
+	expires time.Time
+	ready   bool
+	results map[string]checkResult
 }
 
-func NewHealth() *Health {
-	return &Health{}
+// NewHealth 创建健康检查插件，为空的检查项表示对应组件未配置，直接忽略
+func NewHealth(c *Config, checkers []Checker) *Health {
+	my := &Health{timeout: c.Health.Timeout, ttl: c.Health.TTL}
+	for _, v := range checkers {
+		if v != nil {
+			my.checkers = append(my.checkers, v)
+		}
+	}
+	sort.Slice(my.checkers, func(i, j int) bool {
+		return my.checkers[i].Name() < my.checkers[j].Name()
+	})
+	return my
 }
 
 func (my *Health) Path() string {
@@ -28,32 +142,106 @@ func (my *Health) Check(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":    "ok",
 		"timestamp": time.Now().Unix(),
+		"version":   Version,
 	})
 }
 
-// Liveness 存活检查 - 检查应用是否运行
+// Liveness 存活检查 - 检查应用是否运行，不访问外部依赖
 func (my *Health) Liveness(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":    "alive",
 		"timestamp": time.Now().Unix(),
-		"uptime":    time.Since(time.Now()).Seconds(), // 可以改为实际启动时间
+		"uptime":    time.Since(startTime).Seconds(),
 	})
 }
 
 // Readiness 就绪检查 - 检查应用是否准备好接收流量
+// 关键检查项失败时返回503，只有非关键项失败时返回200并标记为降级
 func (my *Health) Readiness(c fiber.Ctx) error {
-	// 这里可以添加数据库连接、外部服务等检查
-	// 示例：检查数据库连接状态
-	// if !database.IsConnected() {
-	//     return fiber.Map{"status": "not_ready", "reason": "database disconnected"}, nil
-	// }
-
+	if my.closing.Load() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":    "shutting_down",
+			"timestamp": time.Now().Unix(),
+		})
+	}
+	ready, results := my.run()
+	status := "ready"
+	if !ready {
+		status = "not_ready"
+		c.Status(fiber.StatusServiceUnavailable)
+	} else {
+		for _, r := range results {
+			if r.Status != "ok" {
+				status = "degraded"
+				break
+			}
+		}
+	}
 	return c.JSON(fiber.Map{
-		"status":    "ready",
+		"status":    status,
 		"timestamp": time.Now().Unix(),
-		"checks": fiber.Map{
-			"database": "ok",
-			"cache":    "ok",
-		},
+		"checks":    results,
 	})
 }
+
+// Drain 将就绪检查置为失败，使负载均衡不再转发新请求，关闭流程的第一步
+func (my *Health) Drain() {
+	my.closing.Store(true)
+}
+
+// run 返回缓存的检查结果，过期后并发执行所有检查项，同一时刻只有一个请求刷新
+// 结果由多个请求共享，检查不使用请求的上下文，避免探针断开导致缓存失败结果
+func (my *Health) run() (bool, map[string]checkResult) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if my.results != nil && time.Now().Before(my.expires) {
+		return my.ready, my.results
+	}
+
+	results := make([]checkResult, len(my.checkers))
+	var wg sync.WaitGroup
+	for i, v := range my.checkers {
+		wg.Add(1)
+		go func(i int, v Checker) {
+			defer wg.Done()
+			results[i] = my.check(context.Background(), v)
+		}(i, v)
+	}
+	wg.Wait()
+
+	my.ready, my.results = true, make(map[string]checkResult, len(results))
+	for i, r := range results {
+		my.results[my.checkers[i].Name()] = r
+		if r.Critical && r.Status != "ok" {
+			my.ready = false
+		}
+	}
+	my.expires = time.Now().Add(my.ttl)
+	return my.ready, my.results
+}
+
+// check 在超时时间内执行单个检查项，检查项未响应上下文取消时按超时返回
+func (my *Health) check(ctx context.Context, v Checker) checkResult {
+	if my.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, my.timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- v.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	r := checkResult{Status: "ok", Critical: v.Critical(), Latency: time.Since(start).String()}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s", my.timeout)
+		}
+		r.Status, r.Error = "error", err.Error()
+	}
+	return r
+}
@@ -0,0 +1,105 @@
+package std
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readiness 请求就绪检查并解析响应
+func readiness(t *testing.T, app *fiber.App) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	require.NoError(t, err)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestHealthReadiness(t *testing.T) {
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	var calls atomic.Int32
+	var critical, optional error
+	cfg := mockConfig("TestApp", "development", "8080")
+	cfg.Health.Timeout, cfg.Health.TTL = 50*time.Millisecond, time.Minute
+
+	health := NewHealth(cfg, []Checker{
+		NewDatabaseChecker(openTestDB(t)),
+		NewEventChecker(bus),
+		NewRedisChecker(nil),
+		NewNatsChecker(nil),
+		NewChecker("critical", true, func(context.Context) error { calls.Add(1); return critical }),
+		NewChecker("optional", false, func(context.Context) error { return optional }),
+	})
+	app := fiber.New()
+	health.Bind(app.Group(health.Path()))
+
+	status, body := readiness(t, app)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ready", body["status"])
+	checks := body["checks"].(map[string]interface{})
+	assert.Len(t, checks, 4, "未配置的组件不注册检查项")
+	db := checks["database"].(map[string]interface{})
+	assert.Equal(t, "ok", db["status"])
+	assert.Equal(t, true, db["critical"])
+	assert.NotEmpty(t, db["latency"])
+
+	t.Run("缓存期内不重复检查", func(t *testing.T) {
+		critical = errors.New("down")
+		status, _ := readiness(t, app)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("非关键项失败时降级", func(t *testing.T) {
+		critical, optional = nil, errors.New("unreachable")
+		health.expires = time.Time{}
+		status, body := readiness(t, app)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "degraded", body["status"])
+		assert.Equal(t, "unreachable", body["checks"].(map[string]interface{})["optional"].(map[string]interface{})["error"])
+	})
+
+	t.Run("关键项失败时返回503", func(t *testing.T) {
+		critical, optional = errors.New("down"), nil
+		health.expires = time.Time{}
+		status, body := readiness(t, app)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, "not_ready", body["status"])
+	})
+
+	t.Run("检查超时", func(t *testing.T) {
+		blocked := NewHealth(cfg, []Checker{NewChecker("blocked", true, func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})})
+		start := time.Now()
+		ready, results := blocked.run()
+		assert.False(t, ready)
+		assert.Less(t, time.Since(start), time.Second, "不响应取消的检查项也按超时返回")
+		assert.Equal(t, "timeout after 50ms", results["blocked"].Error)
+	})
+}
+
+func TestHealthLiveness(t *testing.T) {
+	health := NewHealth(mockConfig("TestApp", "development", "8080"), nil)
+	app := fiber.New()
+	health.Bind(app.Group(health.Path()))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	require.NoError(t, err)
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Positive(t, body["uptime"], "运行时长从进程启动开始计算")
+}
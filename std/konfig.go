@@ -144,6 +144,27 @@ func NewKonfig(opts ...KonfigOption) (*Konfig, error) {
 		// 限流中间件设置
 		"app.fiber.limiter_max":        100,
 		"app.fiber.limiter_expiration": "1m",
+		// 链路追踪设置
+		"app.tracing.enable":   false,
+		"app.tracing.exporter": "otlp",
+		"app.tracing.ratio":    1.0,
+		// 数据库连接设置
+		"app.pool.max_open":     90,
+		"app.pool.max_idle":     5,
+		"app.pool.max_lifetime": "5m",
+		"app.retry.attempts":    5,
+		"app.retry.backoff":     "1s",
+		"app.retry.max_backoff": "30s",
+		"app.sql_log.slow":      "200ms",
+		// 版本化迁移设置
+		"app.migrate.dir":   "migrations",
+		"app.migrate.table": "schema_migrations",
+		// 健康检查设置
+		"app.health.timeout": "3s",
+		"app.health.ttl":     "5s",
+		// 优雅关闭设置
+		"app.shutdown.drain":   "3s",
+		"app.shutdown.timeout": "10s",
 	}
 
 	// 创建Konfig实例
@@ -514,6 +535,16 @@ func (my *Konfig) notifyCallbacks(newK, _ *koanf.Koanf) {
 	}
 }
 
+// OnConfigChange 运行时注册配置变更回调函数
+func (my *Konfig) OnConfigChange(callback func(*koanf.Koanf)) {
+	if callback == nil {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.callbacks = append(my.callbacks, callback)
+}
+
 // StopWatch 停止配置监听
 func (my *Konfig) StopWatch() {
 	if atomic.CompareAndSwapInt32(&my.watchActive, 1, 0) {
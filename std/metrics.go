@@ -0,0 +1,182 @@
+package std
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Metrics Prometheus监控指标插件，挂载在/metrics
+// 指标注册在独立的Registry上，包含HTTP请求、GraphQL操作、数据库连接池、缓存命中和事件总线计数
+type Metrics struct {
+	registry   *prometheus.Registry
+	requests   *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	operations *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+}
+
+// NewMetrics 创建监控指标插件，数据库、缓存和事件总线均可为空，为空时不采集对应指标
+func NewMetrics(db *gorm.DB, c cache.Cache, bus *event.Bus) (*Metrics, error) {
+	my := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP请求数，按方法、路由模板和状态码统计",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP请求耗时，按方法、路由模板和状态码统计",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "graphql_operations_total",
+			Help: "GraphQL操作数，按操作类型、名称和结果统计",
+		}, []string{"type", "name", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "graphql_operation_duration_seconds",
+			Help:    "GraphQL操作耗时，按操作类型和名称统计",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"type", "name"}),
+	}
+	list := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		my.requests, my.latency, my.operations, my.durations,
+		newStatsCollector(c, bus),
+	}
+	if db != nil {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, collectors.NewDBStatsCollector(sqlDB, db.Dialector.Name()))
+	}
+	for _, c := range list {
+		if err := my.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return my, nil
+}
+
+func (my *Metrics) Path() string {
+	return "/metrics"
+}
+
+func (my *Metrics) Bind(r fiber.Router) {
+	r.Get("/", adaptor.HTTPHandler(promhttp.HandlerFor(my.registry, promhttp.HandlerOpts{})))
+}
+
+// Registry 返回指标注册表，业务可在其上注册自定义指标
+func (my *Metrics) Registry() *prometheus.Registry {
+	return my.registry
+}
+
+// Filter 返回统计HTTP请求的过滤器，挂载在根路径上，对之后注册的所有路由生效
+func (my *Metrics) Filter() Plugin {
+	return metricsFilter{my}
+}
+
+// ObserveOperation 记录一次GraphQL操作，匿名操作的名称为空，空的Metrics不做任何事
+// 名称作为指标标签，调用方只应传入取值有限的可信名称，不能直接使用客户端提交的操作名
+func (my *Metrics) ObserveOperation(kind, name string, elapsed time.Duration, failed bool) {
+	if my == nil {
+		return
+	}
+	status := "ok"
+	if failed {
+		status = "error"
+	}
+	my.operations.WithLabelValues(kind, name, status).Inc()
+	my.durations.WithLabelValues(kind, name).Observe(elapsed.Seconds())
+}
+
+// metricsFilter HTTP请求指标过滤器
+type metricsFilter struct {
+	m *Metrics
+}
+
+func (my metricsFilter) Path() string {
+	return "/"
+}
+
+func (my metricsFilter) Bind(r fiber.Router) {
+	r.Use(my.handle)
+}
+
+// handle 在请求结束后按路由模板记录请求数和耗时，未匹配的请求停留在过滤器自身的路由上，统一记为空路由以避免高基数
+func (my metricsFilter) handle(c fiber.Ctx) error {
+	start, self := time.Now(), c.Route()
+	err := c.Next()
+	route := ""
+	if r := c.Route(); r != self {
+		route = r.Path
+	}
+	status := strconv.Itoa(responseStatus(c, err))
+	my.m.requests.WithLabelValues(c.Method(), route, status).Inc()
+	my.m.latency.WithLabelValues(c.Method(), route, status).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// responseStatus 返回请求最终的状态码，处理器返回的错误由ErrorHandler写入响应，这里按错误推断
+func responseStatus(c fiber.Ctx, err error) int {
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+	status := c.Response().StatusCode()
+	if err != nil && status < fiber.StatusBadRequest {
+		return fiber.StatusInternalServerError
+	}
+	return status
+}
+
+var (
+	cacheHits      = prometheus.NewDesc("cache_hits_total", "缓存命中次数", nil, nil)
+	cacheMisses    = prometheus.NewDesc("cache_misses_total", "缓存未命中次数", nil, nil)
+	cacheEvictions = prometheus.NewDesc("cache_evictions_total", "缓存因容量不足淘汰的key数", nil, nil)
+	eventPublished = prometheus.NewDesc("event_published_total", "事件发布成功次数", []string{"topic"}, nil)
+	eventFailed    = prometheus.NewDesc("event_publish_errors_total", "事件发布失败次数", []string{"topic"}, nil)
+	eventErrors    = prometheus.NewDesc("event_handler_errors_total", "事件处理器返回错误次数", []string{"topic"}, nil)
+)
+
+// statsCollector 在采集时读取缓存和事件总线的内置计数
+type statsCollector struct {
+	cache cache.Reporter
+	bus   *event.Bus
+}
+
+func newStatsCollector(c cache.Cache, bus *event.Bus) *statsCollector {
+	r, _ := c.(cache.Reporter)
+	return &statsCollector{cache: r, bus: bus}
+}
+
+func (my *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{cacheHits, cacheMisses, cacheEvictions, eventPublished, eventFailed, eventErrors} {
+		ch <- d
+	}
+}
+
+func (my *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	if my.cache != nil {
+		s := my.cache.Stats()
+		ch <- prometheus.MustNewConstMetric(cacheHits, prometheus.CounterValue, float64(s.Hits))
+		ch <- prometheus.MustNewConstMetric(cacheMisses, prometheus.CounterValue, float64(s.Misses))
+		ch <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(s.Evictions))
+	}
+	if my.bus != nil {
+		for topic, s := range my.bus.Stats() {
+			ch <- prometheus.MustNewConstMetric(eventPublished, prometheus.CounterValue, float64(s.Published), topic)
+			ch <- prometheus.MustNewConstMetric(eventFailed, prometheus.CounterValue, float64(s.PublishErrors), topic)
+			ch <- prometheus.MustNewConstMetric(eventErrors, prometheus.CounterValue, float64(s.HandlerErrors), topic)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	db := openTestDB(t)
+	store, err := cache.New(nil)
+	require.NoError(t, err)
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	m, err := NewMetrics(db, store, bus)
+	require.NoError(t, err)
+
+	validator, err := NewValidator()
+	require.NoError(t, err)
+	app := NewFiber(mockConfig("TestApp", "development", "8080"), validator)
+	for _, p := range []Plugin{m.Filter(), m} {
+		p.Bind(app.Group(p.Path()))
+	}
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return fiber.ErrNotFound
+		}
+		return c.SendString("ok")
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/missing"} {
+		_, err = app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/users/:id", "200")), "按路由模板而非实际路径统计")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/users/:id", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "", "404")), "未匹配的路由记为空路由")
+
+	m.ObserveOperation("query", "users", time.Millisecond, false)
+	m.ObserveOperation("query", "users", time.Millisecond, true)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("query", "users", "error")))
+	var empty *Metrics
+	assert.NotPanics(t, func() { empty.ObserveOperation("query", "", time.Millisecond, false) })
+
+	ctx := context.Background()
+	require.NoError(t, store.Set(ctx, "k", []byte("v"), time.Minute))
+	// 内存缓存异步写入，写入生效前的读取计为未命中
+	misses := 1
+	require.Eventually(t, func() bool {
+		if _, err := store.Get(ctx, "k"); err != nil {
+			misses++
+			return false
+		}
+		return true
+	}, time.Second, time.Millisecond)
+	_, _ = store.Get(ctx, "none")
+	topic := event.Topic[string]("test:metrics")
+	require.NoError(t, event.Subscribe(ctx, bus, topic, func(context.Context, string) error { return errors.New("boom") }))
+	require.NoError(t, event.Publish(ctx, bus, topic, "hello"))
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/metrics", nil))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`graphql_operations_total{name="users",status="ok",type="query"} 1`,
+		`cache_hits_total 1`,
+		fmt.Sprintf("cache_misses_total %d", misses),
+		`event_published_total{topic="test:metrics"} 1`,
+		`event_handler_errors_total{topic="test:metrics"} 1`,
+		`go_sql_max_open_connections{db_name="sqlite"}`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
@@ -0,0 +1,370 @@
+package std
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migration.go 负责版本化迁移，与 migrate.go 的实体迁移扩展互补：
+// AutoMigrate 只增不减，删除、重命名字段和回滚需要显式的 up/down 迁移。
+// 迁移来源为嵌入的 SQL 文件和 Go 函数，按版本号统一排序，执行记录写入迁移表。
+
+var (
+	// migrationFile 迁移文件名格式：{版本}_{名称}.up.sql / {版本}_{名称}.down.sql，版本通常为创建时间 20060102150405
+	migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^\w+$`)
+)
+
+// migrationLockTimeout MySQL 等待迁移锁的秒数
+const migrationLockTimeout = 300
+
+// MigrateFunc Go 编写的迁移步骤，在事务中执行
+type MigrateFunc func(tx *gorm.DB) error
+
+// Migration 一个版本的迁移，SQL 和 Go 函数二选一
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	UpFunc   MigrateFunc
+	DownFunc MigrateFunc
+}
+
+// Checksum SQL 迁移内容的摘要，用于发现已执行的迁移被修改，Go 迁移无法校验时为空
+func (my *Migration) Checksum() string {
+	if my.UpFunc != nil || my.Up == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(my.Up + "\x00" + my.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+// String 返回 {版本}_{名称}
+func (my *Migration) String() string {
+	return fmt.Sprintf("%d_%s", my.Version, my.Name)
+}
+
+func (my *Migration) run(tx *gorm.DB, up bool) error {
+	fn, script := my.UpFunc, my.Up
+	if !up {
+		fn, script = my.DownFunc, my.Down
+	}
+	switch {
+	case fn != nil:
+		return fn(tx)
+	case script != "":
+		return tx.Exec(script).Error
+	case up:
+		return fmt.Errorf("migrate: %s has no up migration", my)
+	default:
+		return fmt.Errorf("migrate: %s has no down migration", my)
+	}
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // 未执行时为空
+	Modified  bool       // 执行后迁移内容被修改
+	Missing   bool       // 已执行但找不到对应的迁移
+}
+
+// schemaMigration 迁移表的记录
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+var (
+	migrationSources []fs.FS
+	migrationFuncs   []*Migration
+)
+
+// RegisterMigrations 注册嵌入的迁移文件（通常在 init() 中以 embed.FS 调用），子目录中的文件同样会被读取
+func RegisterMigrations(fsys fs.FS) {
+	migrationSources = append(migrationSources, fsys)
+}
+
+// RegisterMigration 注册 Go 编写的迁移，适用于数据回填等无法用 SQL 表达的变更
+func RegisterMigration(version int64, name string, up, down MigrateFunc) {
+	migrationFuncs = append(migrationFuncs, &Migration{Version: version, Name: name, UpFunc: up, DownFunc: down})
+}
+
+// Migrator 版本化迁移的执行器
+// 执行期间持有数据库的咨询锁，多个实例同时启动时依次执行，后获得锁的实例看到的已是最新状态
+type Migrator struct {
+	db      *gorm.DB
+	table   string
+	sources []fs.FS
+}
+
+// NewMigrator 创建迁移执行器，未注册嵌入的迁移文件时从配置的目录读取
+// db 不能启用读写分离，命令行等独立执行迁移时使用 NewMigrationDatabase 连接
+func NewMigrator(db *gorm.DB, c *Config) *Migrator {
+	sources := migrationSources
+	if len(sources) == 0 && c.Migrate.Dir != "" {
+		sources = []fs.FS{os.DirFS(c.Migrate.Dir)}
+	}
+	table := c.Migrate.Table
+	if table == "" {
+		table = "schema_migrations"
+	}
+	return &Migrator{db: db, table: table, sources: sources}
+}
+
+// NewMigrationDatabase 按配置只连接主库用于迁移，不启用读写分离，也不在连接时自动执行迁移
+// 读写分离插件会把事务外的语句改派到其他连接，咨询锁的加锁、解锁和迁移表读取必须留在同一连接上
+func NewMigrationDatabase(c *Config) (*gorm.DB, error) {
+	cc := *c
+	cc.Replicas = nil
+	cc.Migrate.Auto = false
+	return NewDatabase(nil, nil, &cc)
+}
+
+// Migrations 读取所有迁移并按版本排序，版本重复时返回错误
+func (my *Migrator) Migrations() ([]*Migration, error) {
+	versions := map[int64]*Migration{}
+	for _, fsys := range my.sources {
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == "." && errors.Is(err, fs.ErrNotExist) {
+					return fs.SkipAll
+				}
+				return err
+			}
+			match := migrationFile.FindStringSubmatch(d.Name())
+			if d.IsDir() || match == nil {
+				return nil
+			}
+			version, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("migrate: invalid version in %s: %w", p, err)
+			}
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			m, ok := versions[version]
+			if !ok {
+				m = &Migration{Version: version, Name: match[2]}
+				versions[version] = m
+			} else if m.Name != match[2] {
+				return fmt.Errorf("migrate: duplicate version %d: %s and %s", version, m.Name, match[2])
+			}
+			if match[3] == "up" {
+				m.Up = string(data)
+			} else {
+				m.Down = string(data)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range migrationFuncs {
+		if m, ok := versions[f.Version]; ok {
+			return nil, fmt.Errorf("migrate: duplicate version %d: %s and %s", f.Version, m.Name, f.Name)
+		}
+		versions[f.Version] = f
+	}
+
+	list := make([]*Migration, 0, len(versions))
+	for _, m := range versions {
+		if m.Up == "" && m.UpFunc == nil {
+			return nil, fmt.Errorf("migrate: %s has no up migration", m)
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Up 按版本顺序执行所有未执行的迁移，已执行的迁移内容被修改时拒绝执行
+// 每个迁移在独立事务中执行并写入记录，失败时停止，之前的迁移保持已执行
+func (my *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	list, err := my.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []*Migration
+	err = my.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := my.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			if r, ok := applied[m.Version]; ok {
+				if sum := m.Checksum(); r.Checksum != "" && sum != "" && r.Checksum != sum {
+					return fmt.Errorf("migrate: %s was modified after it was applied", m)
+				}
+				continue
+			}
+			if err = my.apply(conn, m, true); err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本倒序回滚最近执行的 n 个迁移
+func (my *Migrator) Down(ctx context.Context, n int) ([]*Migration, error) {
+	list, err := my.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []*Migration
+	err = my.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := my.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(list) - 1; i >= 0 && len(done) < n; i-- {
+			if _, ok := applied[list[i].Version]; !ok {
+				continue
+			}
+			if err = my.apply(conn, list[i], false); err != nil {
+				return err
+			}
+			done = append(done, list[i])
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 返回所有迁移的执行状态，包括已执行但找不到对应迁移的记录
+func (my *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := my.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	var applied map[int64]schemaMigration
+	err = my.locked(ctx, func(conn *gorm.DB) (err error) {
+		applied, err = my.applied(conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if r, ok := applied[m.Version]; ok {
+			s.AppliedAt = &r.AppliedAt
+			s.Modified = r.Checksum != "" && m.Checksum() != "" && r.Checksum != m.Checksum()
+			delete(applied, m.Version)
+		}
+		result = append(result, s)
+	}
+	for _, r := range applied {
+		result = append(result, MigrationStatus{Version: r.Version, Name: r.Name, AppliedAt: &r.AppliedAt, Missing: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// CreateMigration 在目录下创建以当前时间为版本的 up/down 迁移文件，返回创建的文件路径
+func CreateMigration(dir, name string) ([]string, error) {
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("migrate: invalid name %q, use letters, digits and underscores", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	version := time.Now().UTC().Format("20060102150405")
+	var files []string
+	for _, kind := range []string{"up", "down"} {
+		file := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, kind))
+		content := fmt.Sprintf("-- %s %s\n", filepath.Base(file), kind)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// locked 在固定的连接上持有咨询锁执行，迁移表在加锁后创建
+// 锁与连接绑定，连接归还前释放；SQLite 为单机文件库，不加锁
+func (my *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return my.db.WithContext(ctx).Connection(func(conn *gorm.DB) (err error) {
+		key := int64(crc32.ChecksumIEEE([]byte(my.table)))
+		switch conn.Dialector.Name() {
+		case "postgres":
+			if err = conn.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+				return fmt.Errorf("migrate: acquire lock: %w", err)
+			}
+			defer func() { err = errors.Join(err, conn.Exec("SELECT pg_advisory_unlock(?)", key).Error) }()
+		case "mysql":
+			var got sql.NullInt64
+			if err = conn.Raw("SELECT GET_LOCK(?, ?)", my.table, migrationLockTimeout).Scan(&got).Error; err != nil {
+				return fmt.Errorf("migrate: acquire lock: %w", err)
+			}
+			if got.Int64 != 1 {
+				return fmt.Errorf("migrate: acquire lock: timeout after %ds", migrationLockTimeout)
+			}
+			defer func() { err = errors.Join(err, conn.Exec("SELECT RELEASE_LOCK(?)", my.table).Error) }()
+		}
+		if err = conn.Table(my.table).AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// applied 读取迁移表，绕过查询缓存直接读库
+func (my *Migrator) applied(conn *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	err := conn.Raw("SELECT version, name, checksum, applied_at FROM ?", clause.Table{Name: my.table}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int64]schemaMigration, len(rows))
+	for _, r := range rows {
+		result[r.Version] = r
+	}
+	return result, nil
+}
+
+// apply 在事务中执行迁移并更新迁移表
+func (my *Migrator) apply(conn *gorm.DB, m *Migration, up bool) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := m.run(tx, up); err != nil {
+			return err
+		}
+		if up {
+			return tx.Table(my.table).Create(&schemaMigration{
+				Version: m.Version, Name: m.Name, Checksum: m.Checksum(), AppliedAt: time.Now(),
+			}).Error
+		}
+		return tx.Table(my.table).Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %s: %w", m, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package std
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrator(t *testing.T) {
+	db := openTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	fsys := fstest.MapFS{
+		"migrations/20240101000000_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+		"migrations/20240101000000_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/20240102000000_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"migrations/20240102000000_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"migrations/README.md":                            {Data: []byte("ignored")},
+	}
+	old := migrationFuncs
+	t.Cleanup(func() { migrationFuncs = old })
+	RegisterMigration(20240103000000, "seed_admin", func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO users (name, email) VALUES ('admin', 'admin@example.com')").Error
+	}, func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM users WHERE name = 'admin'").Error
+	})
+
+	m := NewMigrator(db, mockConfig("TestApp", "production", "8080"))
+	m.sources = []fs.FS{fsys}
+	ctx := context.Background()
+
+	done, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, done, 3)
+	assert.Equal(t, "20240103000000_seed_admin", done[2].String())
+	var count int64
+	require.NoError(t, db.Table("users").Where("email IS NOT NULL").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	done, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, done, "已执行的迁移不重复执行")
+
+	done, err = m.Down(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, done, 2)
+	assert.Equal(t, int64(20240103000000), done[0].Version, "按版本倒序回滚")
+	assert.False(t, db.Migrator().HasColumn("users", "email"))
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.NotNil(t, status[0].AppliedAt)
+	assert.Nil(t, status[1].AppliedAt)
+
+	t.Run("已执行的迁移被修改", func(t *testing.T) {
+		fsys["migrations/20240101000000_create_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER);")}
+		_, err := m.Up(ctx)
+		assert.ErrorContains(t, err, "20240101000000_create_users was modified")
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, status[0].Modified)
+	})
+
+	t.Run("找不到已执行的迁移", func(t *testing.T) {
+		delete(fsys, "migrations/20240101000000_create_users.up.sql")
+		delete(fsys, "migrations/20240101000000_create_users.down.sql")
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, status[0].Missing)
+	})
+
+	t.Run("版本重复", func(t *testing.T) {
+		fsys["20240102000000_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+		_, err := m.Migrations()
+		assert.ErrorContains(t, err, "duplicate version 20240102000000")
+	})
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	files, err := CreateMigration(dir, "add_index")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Regexp(t, `^\d{14}_add_index\.up\.sql$`, filepath.Base(files[0]))
+	assert.FileExists(t, files[1])
+
+	cfg := mockConfig("TestApp", "production", "8080")
+	cfg.Migrate.Dir = dir
+	list, err := NewMigrator(openTestDB(t), cfg).Migrations()
+	require.NoError(t, err)
+	require.Len(t, list, 1, "未注册嵌入的迁移时从目录读取")
+	assert.NotEmpty(t, list[0].Down)
+
+	_, err = CreateMigration(dir, "bad name")
+	assert.Error(t, err)
+}
+
+// lockDialector 名为postgres的SQLite方言，迁移时按PostgreSQL执行咨询锁
+type lockDialector struct{ gorm.Dialector }
+
+func (lockDialector) Name() string { return "postgres" }
+
+func TestNewMigrationDatabase(t *testing.T) {
+	// 咨询锁函数记录执行语句的连接
+	var (
+		mu    sync.Mutex
+		calls []string
+		conns atomic.Int32
+	)
+	driver := "sqlite3_" + t.Name()
+	sql.Register(driver, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		id := conns.Add(1)
+		for _, name := range []string{"pg_advisory_lock", "pg_advisory_unlock"} {
+			name := name
+			if err := conn.RegisterFunc(name, func(int64) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, fmt.Sprintf("%s@%d", name, id))
+				return true
+			}, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+	for _, scheme := range []string{"lockprimary", "lockreplica"} {
+		dsn := "file:" + t.Name() + scheme + "?mode=memory&cache=shared"
+		RegisterDatabase(scheme, func(string) gorm.Dialector {
+			return lockDialector{sqlite.Dialector{DriverName: driver, DSN: dsn}}
+		})
+	}
+	cfg := mockConfig("TestApp", "production", "8080")
+	cfg.Database, cfg.Replicas = "lockprimary://test", []string{"lockreplica://test"}
+	cfg.SQLLog.Level = "silent"
+	cfg.Retry.Attempts = 1
+	cfg.Migrate.Auto = true
+
+	db, err := NewMigrationDatabase(cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, db.Config.Plugins, "std:replicas", "迁移连接不启用读写分离")
+	assert.Empty(t, calls, "连接时不自动执行迁移")
+	assert.True(t, cfg.Migrate.Auto, "不修改调用方的配置")
+
+	m := NewMigrator(db, cfg)
+	m.sources = []fs.FS{fstest.MapFS{}}
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	var lock, unlock int
+	_, _ = fmt.Sscanf(calls[0], "pg_advisory_lock@%d", &lock)
+	_, _ = fmt.Sscanf(calls[1], "pg_advisory_unlock@%d", &unlock)
+	assert.NotZero(t, lock)
+	assert.Equal(t, lock, unlock, "加锁和解锁在同一连接上执行")
+}
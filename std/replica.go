@@ -0,0 +1,183 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ichaly/ideabase/log"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaInterval 副本健康探测的间隔
+const replicaInterval = 5 * time.Second
+
+// routeKey 读写路由标记在上下文中的键
+type routeKey struct{}
+
+// UsePrimary 标记上下文中的读操作也使用主库，写入后立即读取时避免副本延迟读到旧数据
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, dbresolver.Write)
+}
+
+// UseReplica 标记上下文中的读写为只读，路由到副本；已标记使用主库的上下文保持不变
+// 以WITH开头等无法按语句判断读写的原生SQL默认使用主库，确认只读时需显式标记
+func UseReplica(ctx context.Context) context.Context {
+	if ctx.Value(routeKey{}) == dbresolver.Write {
+		return ctx
+	}
+	return context.WithValue(ctx, routeKey{}, dbresolver.Read)
+}
+
+// RouteTransaction 按上下文中的标记为即将开启的事务选择连接，事务中的语句固定在该连接上
+// dbresolver默认在主库开启事务，标记为只读且存在健康副本时改为在副本上开启；未启用读写分离时原样返回
+func RouteTransaction(db *gorm.DB) *gorm.DB {
+	r, ok := db.Config.Plugins[(&Replicas{}).Name()].(*Replicas)
+	if !ok || db.Statement.Context == nil {
+		return db
+	}
+	if op, _ := db.Statement.Context.Value(routeKey{}).(dbresolver.Operation); op == dbresolver.Read && r.available() {
+		return db.Clauses(dbresolver.Read)
+	}
+	return db
+}
+
+// Replicas 基于dbresolver的读写分离插件
+// 查询路由到健康的副本并轮询，写操作和事务使用主库，副本全部不可用时回退到主库；只读事务通过RouteTransaction开启在副本上
+type Replicas struct {
+	resolver *dbresolver.DBResolver
+	primary  gorm.ConnPool
+	pools    []gorm.ConnPool
+	healthy  map[gorm.ConnPool]*atomic.Bool
+	next     atomic.Uint64
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewReplicas 创建读写分离插件，副本连接在插件初始化时按主库的配置打开
+func NewReplicas(replicas ...gorm.Dialector) *Replicas {
+	my := &Replicas{healthy: make(map[gorm.ConnPool]*atomic.Bool), stop: make(chan struct{})}
+	my.resolver = dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: my})
+	return my
+}
+
+func (my *Replicas) Name() string { return "std:replicas" }
+
+func (my *Replicas) Initialize(db *gorm.DB) error {
+	my.primary = db.ConnPool
+	if p, ok := my.primary.(*gorm.PreparedStmtDB); ok {
+		my.primary = p.ConnPool
+	}
+	if err := db.Use(my.resolver); err != nil {
+		return err
+	}
+	_ = my.resolver.Call(func(p gorm.ConnPool) error {
+		if p != my.primary {
+			my.pools = append(my.pools, p)
+			my.healthy[p] = &atomic.Bool{}
+			my.healthy[p].Store(true)
+		}
+		return nil
+	})
+
+	name := my.Name()
+	cb := db.Callback()
+	if err := errors.Join(
+		cb.Query().Before("*").Register(name, my.route),
+		cb.Row().Before("*").Register(name, my.route),
+		cb.Raw().Before("*").Register(name, my.route),
+	); err != nil {
+		return err
+	}
+	go my.watch()
+	return nil
+}
+
+// Resolve 从当前副本开始轮询，跳过探测失败的副本，全部不可用时使用主库
+func (my *Replicas) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	n := uint64(len(pools))
+	start := my.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		p := pools[(start+i)%n]
+		if h, ok := my.healthy[p]; !ok || h.Load() {
+			return p
+		}
+	}
+	return my.primary
+}
+
+// route 按上下文中的标记指定读写，副本全部不可用时读操作也改为主库
+// dbresolver只有一个副本时不经过Resolve，回退主库需在这里处理
+// 标记写入语句后会重新触发dbresolver的路由，与回调的先后顺序无关；事务中的语句由dbresolver固定在事务连接上
+func (my *Replicas) route(db *gorm.DB) {
+	var op dbresolver.Operation
+	if ctx := db.Statement.Context; ctx != nil {
+		op, _ = ctx.Value(routeKey{}).(dbresolver.Operation)
+	}
+	if op != dbresolver.Write && !my.available() {
+		op = dbresolver.Write
+	}
+	if op != "" {
+		op.ModifyStatement(db.Statement)
+	}
+}
+
+// available 是否存在健康的副本
+func (my *Replicas) available() bool {
+	for _, h := range my.healthy {
+		if h.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// watch 定期探测副本，状态变化时记录日志
+func (my *Replicas) watch() {
+	ticker := time.NewTicker(replicaInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-my.stop:
+			return
+		case <-ticker.C:
+			my.probe()
+		}
+	}
+}
+
+// probe 探测所有副本的连通性
+func (my *Replicas) probe() {
+	for _, p := range my.pools {
+		pinger, ok := p.(interface{ PingContext(context.Context) error })
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), replicaInterval)
+		err := pinger.PingContext(ctx)
+		cancel()
+		if my.healthy[p].Swap(err == nil) != (err == nil) {
+			if err != nil {
+				log.Warn().Err(err).Msg("数据库副本不可用，读操作暂时绕过")
+			} else {
+				log.Info().Msg("数据库副本已恢复")
+			}
+		}
+	}
+}
+
+// Close 停止探测并关闭副本连接，主库由调用方关闭
+func (my *Replicas) Close() error {
+	my.once.Do(func() { close(my.stop) })
+	var errs []error
+	for _, p := range my.pools {
+		if c, ok := p.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
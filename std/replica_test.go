@@ -0,0 +1,77 @@
+package std
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type replicaUser struct {
+	ID   uint
+	Name string
+}
+
+// openReplica 打开共享缓存的内存库作为副本，保持一个连接使数据在连接间可见
+func openReplica(t *testing.T) gorm.Dialector {
+	dsn := "file:" + t.Name() + "_replica?mode=memory&cache=shared"
+	holder, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, holder.AutoMigrate(&replicaUser{}))
+	require.NoError(t, holder.Create(&replicaUser{Name: "replica"}).Error)
+	t.Cleanup(func() {
+		sqlDB, _ := holder.DB()
+		_ = sqlDB.Close()
+	})
+	return sqlite.Open(dsn)
+}
+
+func TestReplicas(t *testing.T) {
+	db := openTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&replicaUser{}))
+	require.NoError(t, db.Create(&replicaUser{Name: "primary"}).Error)
+
+	replicas := NewReplicas(openReplica(t))
+	require.NoError(t, db.Use(replicas))
+	t.Cleanup(func() { _ = replicas.Close() })
+
+	name := func(tx *gorm.DB) string {
+		var u replicaUser
+		require.NoError(t, tx.First(&u).Error)
+		return u.Name
+	}
+	raw := func(ctx context.Context) string {
+		var names []string
+		require.NoError(t, db.WithContext(ctx).Raw("WITH t AS (SELECT name FROM replica_users) SELECT name FROM t").Scan(&names).Error)
+		return names[0]
+	}
+	ctx := context.Background()
+
+	assert.Equal(t, "replica", name(db), "查询路由到副本")
+	assert.Equal(t, "primary", name(db.WithContext(UsePrimary(ctx))), "写后读强制使用主库")
+	assert.Equal(t, "primary", raw(ctx), "无法判断读写的原生SQL使用主库")
+	assert.Equal(t, "replica", raw(UseReplica(ctx)))
+	assert.Equal(t, "primary", raw(UseReplica(UsePrimary(ctx))), "主库标记优先")
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, "primary", name(tx), "事务固定在主库")
+		return nil
+	}))
+
+	require.NoError(t, db.Create(&replicaUser{Name: "written"}).Error)
+	var count int64
+	require.NoError(t, db.WithContext(UsePrimary(ctx)).Model(&replicaUser{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count, "写操作使用主库")
+
+	// 副本断开后探测失败，读操作回退到主库
+	require.NoError(t, replicas.pools[0].(io.Closer).Close())
+	replicas.probe()
+	assert.Equal(t, "primary", name(db))
+}
@@ -0,0 +1,124 @@
+package std
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/log"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Shutdown 应用的有序关闭流程，由Bootstrap在应用停止时执行
+// 依次为：就绪检查置为失败、等待排空、关闭HTTP服务、关闭事件订阅、刷新缓存、关闭数据库、Redis和NATS连接，最后刷新日志
+// 除配置和健康检查外均可为空，为空时跳过对应步骤
+type Shutdown struct {
+	drain   time.Duration
+	timeout time.Duration
+	health  *Health
+	bus     *event.Bus
+	cache   cache.Cache
+	db      *gorm.DB
+	rdb     redis.UniversalClient
+	nc      *nats.Conn
+}
+
+// NewShutdown 创建关闭流程
+func NewShutdown(c *Config, h *Health, bus *event.Bus, cc cache.Cache, db *gorm.DB, rdb redis.UniversalClient, nc *nats.Conn) *Shutdown {
+	return &Shutdown{
+		drain:   c.Shutdown.Drain,
+		timeout: c.Shutdown.Timeout,
+		health:  h,
+		bus:     bus,
+		cache:   cc,
+		db:      db,
+		rdb:     rdb,
+		nc:      nc,
+	}
+}
+
+// Run 按顺序关闭应用，某一步失败不影响后续步骤，错误合并返回
+// 排空等待和HTTP关闭受上下文约束，上下文取消时提前结束等待；空的Shutdown只关闭HTTP服务
+func (my *Shutdown) Run(ctx context.Context, a *fiber.App) error {
+	if my == nil {
+		return a.ShutdownWithContext(ctx)
+	}
+	var errs []error
+	step := func(name string, fn func() error) {
+		if err := fn(); err != nil {
+			log.Error().Err(err).Str("step", name).Msg("关闭失败")
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	// 先让负载均衡摘除实例，再停止接收请求，避免摘除前的请求被拒绝
+	if my.health != nil {
+		my.health.Drain()
+	}
+	if my.drain > 0 {
+		select {
+		case <-time.After(my.drain):
+		case <-ctx.Done():
+		}
+	}
+	step("fiber", func() error {
+		if my.timeout <= 0 {
+			return a.ShutdownWithContext(ctx)
+		}
+		c, cancel := context.WithTimeout(ctx, my.timeout)
+		defer cancel()
+		return a.ShutdownWithContext(c)
+	})
+
+	// 请求处理结束后再关闭订阅，变更广播不会丢失；缓存在连接关闭前刷新
+	if my.bus != nil {
+		step("event", my.bus.Close)
+	}
+	if c, ok := my.cache.(io.Closer); ok {
+		step("cache", c.Close)
+	}
+
+	if my.db != nil {
+		step("database", func() error {
+			// 副本等插件持有的连接先于主库关闭
+			var errs []error
+			for _, p := range my.db.Config.Plugins {
+				if c, ok := p.(io.Closer); ok {
+					errs = append(errs, c.Close())
+				}
+			}
+			sqlDB, err := my.db.DB()
+			if err == nil {
+				err = sqlDB.Close()
+			}
+			return errors.Join(append(errs, err)...)
+		})
+	}
+	if my.rdb != nil {
+		step("redis", my.rdb.Close)
+	}
+	if my.nc != nil {
+		step("nats", func() error {
+			// 发送缓冲区中未发出的消息后再断开
+			err := my.nc.FlushTimeout(time.Second)
+			my.nc.Close()
+			if errors.Is(err, nats.ErrConnectionClosed) {
+				return nil
+			}
+			return err
+		})
+	}
+
+	// 日志最后关闭，之前各步骤的错误都能写入日志；关闭后不再通过log输出，避免重新打开日志文件
+	if err := log.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package std
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/cache"
+	"github.com/ichaly/ideabase/std/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	cfg := mockConfig("TestApp", "development", "8080")
+	cfg.Shutdown.Drain, cfg.Shutdown.Timeout = 100*time.Millisecond, time.Second
+	db := openTestDB(t)
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	store, err := cache.New(nil)
+	require.NoError(t, err)
+
+	topic := event.Topic[string]("test:shutdown")
+	received := 0
+	require.NoError(t, event.Subscribe(context.Background(), bus, topic, func(context.Context, string) error {
+		received++
+		return nil
+	}))
+
+	health := NewHealth(cfg, []Checker{NewDatabaseChecker(db)})
+	app := fiber.New()
+	health.Bind(app.Group(health.Path()))
+	s := NewShutdown(cfg, health, bus, store, db, nil, nil)
+
+	require.NoError(t, store.Set(context.Background(), "k", []byte("v"), time.Minute))
+
+	// 排空期间就绪检查已失败，HTTP服务仍可处理请求
+	status := make(chan int, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		if err == nil {
+			status <- resp.StatusCode
+		}
+		close(status)
+	}()
+	start := time.Now()
+	require.NoError(t, s.Run(context.Background(), app))
+	assert.GreaterOrEqual(t, time.Since(start), cfg.Shutdown.Drain, "等待排空时间后再关闭HTTP服务")
+	assert.Equal(t, http.StatusServiceUnavailable, <-status)
+
+	require.NoError(t, event.Publish(context.Background(), bus, topic, "after"))
+	assert.Zero(t, received, "订阅已关闭")
+	_, err = store.Get(context.Background(), "k")
+	assert.ErrorIs(t, err, cache.ErrNotFound, "缓存已释放")
+	assert.Error(t, db.Exec("SELECT 1").Error, "数据库连接已关闭")
+}
+
+func TestShutdownDrainCanceled(t *testing.T) {
+	cfg := mockConfig("TestApp", "development", "8080")
+	cfg.Shutdown.Drain = time.Minute
+	s := NewShutdown(cfg, nil, nil, nil, nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, s.Run(ctx, fiber.New()))
+	assert.Less(t, time.Since(start), time.Second, "上下文取消时提前结束排空等待")
+
+	var empty *Shutdown
+	assert.NoError(t, empty.Run(context.Background(), fiber.New()))
+}
@@ -0,0 +1,114 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer HTTP请求与数据库语句的链路追踪器，未启用时全局提供者为空实现
+var tracer = otel.Tracer("github.com/ichaly/ideabase/std")
+
+// InitTracing 根据配置初始化 OpenTelemetry 链路追踪，设置全局的 TracerProvider 和 W3C traceparent 传播器
+// 未启用时保持全局空实现，各处埋点不产生开销；应用关闭时导出剩余的 span
+func InitTracing(c *Config, l Lifecycle) error {
+	conf := c.Tracing
+	if !conf.Enable {
+		return nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch conf.Exporter {
+	case "", "otlp":
+		var opts []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return fmt.Errorf("tracing: unsupported exporter %q", conf.Exporter)
+	}
+	if err != nil {
+		return fmt.Errorf("tracing: create exporter failed: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.Ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", c.Name),
+			attribute.String("service.version", Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	l.Append(nil, provider.Shutdown)
+	return nil
+}
+
+// fiberCarrier 以请求头读取、响应头写入的方式适配传播器
+type fiberCarrier struct {
+	c fiber.Ctx
+}
+
+func (my fiberCarrier) Get(key string) string { return my.c.Get(key) }
+func (my fiberCarrier) Set(key, value string) { my.c.Set(key, value) }
+func (my fiberCarrier) Keys() []string {
+	headers := my.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// newTracingHandler 为每个请求创建服务端 span，上游的 traceparent 作为父上下文
+// span 写入请求上下文，后续的 GraphQL 操作和数据库语句成为其子 span
+func newTracingHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), fiberCarrier{c})
+		ctx, span := tracer.Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+				attribute.String("client.address", c.IP()),
+			),
+		)
+		defer span.End()
+		c.SetContext(ctx)
+
+		err := c.Next()
+		// 路由在匹配后才能确定，span 名称使用路由模板避免高基数
+		if route := c.Route(); route != nil && route.Path != "" {
+			span.SetName(c.Method() + " " + route.Path)
+			span.SetAttributes(attribute.String("http.route", route.Path))
+		}
+		status := responseStatus(c, err)
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if err != nil {
+			span.RecordError(err)
+		}
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+		return err
+	}
+}
@@ -0,0 +1,105 @@
+package std
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ichaly/ideabase/std/event"
+	_ "github.com/ichaly/ideabase/std/event/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spanExporter = tracetest.NewInMemoryExporter()
+	spanOnce     sync.Once
+)
+
+// useSpanExporter 将全局 TracerProvider 设置为同步导出到内存的实现
+// 各包的 tracer 在首次设置时完成委托，因此全局只设置一次，每个测试开始前清空已导出的 span
+func useSpanExporter() *tracetest.InMemoryExporter {
+	spanOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spanExporter.Reset()
+	return spanExporter
+}
+
+// spanNamed 按名称查找已导出的 span
+func spanNamed(exporter *tracetest.InMemoryExporter, name string) *tracetest.SpanStub {
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+func TestTracingRequest(t *testing.T) {
+	exporter := useSpanExporter()
+	db := openTestDB(t)
+	require.NoError(t, db.Use(NewGormTracing()))
+	require.NoError(t, db.AutoMigrate(&cacheUser{}))
+
+	cfg := mockConfig("TestApp", "development", "8080")
+	cfg.Tracing.Enable = true
+	validator, err := NewValidator()
+	require.NoError(t, err)
+	app := NewFiber(cfg, validator)
+	app.Get("/users/:id", func(c fiber.Ctx) error {
+		var users []cacheUser
+		return db.WithContext(c.Context()).Where("id = ?", c.Params("id")).Find(&users).Error
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err = app.Test(req)
+	require.NoError(t, err)
+
+	server := spanNamed(exporter, "GET /users/:id")
+	require.NotNil(t, server, "span 以路由模板命名")
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String(), "延续上游的 traceparent")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+
+	query := spanNamed(exporter, "gorm.query")
+	require.NotNil(t, query)
+	assert.Equal(t, server.SpanContext.SpanID(), query.Parent.SpanID(), "数据库语句挂在请求 span 下")
+	assert.Contains(t, query.Attributes, attribute.String("db.collection.name", "cache_users"))
+}
+
+func TestTracingEvent(t *testing.T) {
+	exporter := useSpanExporter()
+	bus, err := event.New(nil, nil, nil)
+	require.NoError(t, err)
+	topic := event.Topic[event.TableChange]("test:tracing")
+
+	var received event.TableChange
+	var parent trace.SpanContext
+	require.NoError(t, event.Subscribe(context.Background(), bus, topic, func(ctx context.Context, c event.TableChange) error {
+		received, parent = c, trace.SpanContextFromContext(ctx)
+		return nil
+	}))
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "caller")
+	require.NoError(t, event.Publish(ctx, bus, topic, event.TableChange{Table: "users", Action: event.ActionCreate}))
+	span.End()
+
+	assert.Equal(t, event.TableChange{Table: "users", Action: event.ActionCreate}, received, "订阅方收到解开信封后的载荷")
+	assert.Equal(t, span.SpanContext().TraceID(), parent.TraceID(), "消息头携带链路上下文")
+	publish := spanNamed(exporter, "publish test:tracing")
+	process := spanNamed(exporter, "process test:tracing")
+	require.NotNil(t, publish)
+	require.NotNil(t, process)
+	assert.Equal(t, publish.SpanContext.SpanID(), process.Parent.SpanID())
+}